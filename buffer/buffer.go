@@ -3,18 +3,120 @@
 
 package buffer
 
-import "errors"
+import (
+	"errors"
+	"sort"
+	"time"
+)
 
 var ErrInvalidPos = errors.New("invalid cursor position")
 var ErrMaxSizeExceeded = errors.New("max size exceeded")
 var ErrLineEmpty = errors.New("line already empty")
 
-// Buffer struct.
+// The store a piece's runes live in. Both stores are append-only/immutable
+// once written, so pieces can reference ranges of them without copying.
+type source int
+
+const (
+	sourceOriginal source = iota
+	sourceAdd
+)
+
+// A piece is a reference to a contiguous run of runes in one of the two
+// stores. The document is the ordered concatenation of its pieces.
+type piece struct {
+	source source
+	start  int
+	length int
+}
+
+// Buffer struct. Internally, the document is represented as a piece table:
+// the runes slurped from disk on load never move (the "original" store),
+// edits are appended to a second append-only store (the "add" store), and
+// the visible document is an ordered list of pieces referencing ranges of
+// either store. Inserting or deleting a single rune only ever splits or
+// resizes one or two pieces, so edits stay cheap regardless of file size.
 type Buffer struct {
 	// Internal buffer data.
 	maxSize     int
 	lineMaxSize int
-	data        []*BufferLine
+
+	original         []rune
+	originalNewlines []int
+
+	add         []rune
+	addNewlines []int
+
+	// tree orders the document's pieces in a treap keyed by sequence
+	// position, with per-subtree rune/newline counts (see piecetree.go).
+	// That gives row/column lookups an O(log n) descent, and lets an edit
+	// splice pieces in or out with an O(log n) split/merge instead of
+	// rebuilding a prefix-sum array from the edit point onward.
+	tree *pieceNode
+
+	// Cached row views, invalidated whenever the line count changes.
+	lineViews []*BufferLine
+
+	// Undo/redo state. undoStack/redoStack hold committed transactions;
+	// curTxn accumulates ops that are still eligible to be merged into the
+	// transaction currently being built. applying suppresses op recording
+	// while Undo/Redo are themselves replaying ops through InsertOne/
+	// DeleteOne.
+	undoStack  []transaction
+	redoStack  []transaction
+	curTxn     transaction
+	lastOpAt   time.Time
+	groupDepth int
+	applying   bool
+
+	// version increments on every content change, so callers that cache
+	// derived data (e.g. search matches, syntax highlighting) know when
+	// their cache has gone stale.
+	version int
+
+	// dirty is true whenever the buffer has unsaved changes.
+	dirty bool
+
+	// dirtyFrom is the lowest row touched by an edit since the last
+	// ClearDirty call, so a per-line cache (e.g. syntax highlighting
+	// state) can invalidate just the affected suffix instead of
+	// recomputing from line 0 on every edit.
+	dirtyFrom int
+}
+
+// Version returns a counter that increments every time the buffer's
+// content changes.
+func (b *Buffer) Version() int {
+	return b.version
+}
+
+// Dirty reports whether the buffer has unsaved changes.
+func (b *Buffer) Dirty() bool {
+	return b.dirty
+}
+
+// MarkSaved clears the dirty flag; callers should invoke this once the
+// buffer's content has been written out.
+func (b *Buffer) MarkSaved() {
+	b.dirty = false
+}
+
+// DirtyFrom returns the lowest row edited since the last ClearDirty call.
+func (b *Buffer) DirtyFrom() int {
+	return b.dirtyFrom
+}
+
+// ClearDirty resets the dirty-row marker past the end of the buffer, for a
+// consumer that has just resynced its per-line cache through DirtyFrom.
+func (b *Buffer) ClearDirty() {
+	b.dirtyFrom = b.Size()
+}
+
+// markDirtyFrom records that row has changed, for DirtyFrom.
+func (b *Buffer) markDirtyFrom(row int) {
+	if row < b.dirtyFrom {
+		b.dirtyFrom = row
+	}
 }
 
 // Create a new buffer.
@@ -22,7 +124,6 @@ func NewBuffer(maxSize, lineMaxSize int) *Buffer {
 	return &Buffer{
 		maxSize:     maxSize,
 		lineMaxSize: lineMaxSize,
-		data:        make([]*BufferLine, 0),
 	}
 }
 
@@ -38,17 +139,66 @@ func (b *Buffer) MaxLineSize() int {
 
 // Get line size.
 func (b *Buffer) Size() int {
-	return len(b.data)
+	if b.tree == nil {
+		return 0
+	}
+	return subNL(b.tree) + 1
 }
 
-// Get line data.
+// Get line data. Each line is a lazy view over the piece table; its content
+// is only materialized when something calls BufferLine.Data().
 func (b *Buffer) Data() []*BufferLine {
-	return b.data
+	if b.lineViews == nil {
+		n := b.Size()
+		views := make([]*BufferLine, n)
+		for i := 0; i < n; i++ {
+			views[i] = &BufferLine{maxSize: b.lineMaxSize, owner: b, row: i}
+		}
+		b.lineViews = views
+	}
+	return b.lineViews
 }
 
-// Set line data.
+// Set line data. This slurps the given lines into the "original" store and
+// resets the piece table to a single piece spanning it, the same way a file
+// load would; any subsequent edits are layered on top via the "add" store.
 func (b *Buffer) SetData(lines []*BufferLine) {
-	b.data = lines
+	b.version++
+	b.dirty = false
+	total := 0
+	for _, l := range lines {
+		total += l.Size()
+	}
+	if len(lines) > 0 {
+		total += len(lines) - 1
+	}
+
+	orig := make([]rune, 0, total)
+	for i, l := range lines {
+		orig = append(orig, l.Data()...)
+		if i < len(lines)-1 {
+			orig = append(orig, '\n')
+		}
+	}
+
+	b.dirtyFrom = 0
+	b.original = orig
+	b.originalNewlines = findNewlines(orig)
+	b.add = nil
+	b.addNewlines = nil
+
+	if len(lines) == 0 {
+		b.tree = nil
+	} else {
+		p := piece{source: sourceOriginal, start: 0, length: len(orig)}
+		b.tree = newPieceNode(p, b.pieceNewlineCount(p))
+	}
+	b.invalidateViews()
+
+	// A freshly loaded document has nothing to undo into.
+	b.undoStack = nil
+	b.redoStack = nil
+	b.curTxn = nil
 }
 
 // Insert a char. Returns the new position of the cursor.
@@ -56,31 +206,41 @@ func (b *Buffer) InsertOne(char rune, row, col int) (int, int, error) {
 	if row > b.Size() {
 		return row, col, ErrInvalidPos
 	}
-	if char == rune('\n') {
+	if char == '\n' {
 		// New line.
 		if b.Size()+1 > b.MaxSize() {
 			return row, col, ErrMaxSizeExceeded
 		}
-		origData := make([]*BufferLine, len(b.data))
-		copy(origData, b.data)
-		b.data = make([]*BufferLine, len(b.data)+1)
-
-		// Create the new line and copy over the previous lines.
-		copy(b.data[:row+1], origData[:row+1])
-		copy(b.data[row+2:], origData[row+1:])
-
-		// Split the line.
-		splitLine := origData[row].data
-		b.data[row].data = splitLine[:col]
-		b.data[row+1] = NewBufferLine(b.MaxLineSize())
-		b.data[row+1].data = splitLine[col:]
-
-		// Return.
+		pos, err := b.rowStartOffset(row)
+		if err != nil {
+			return row, col, err
+		}
+		b.insertRune(pos+col, '\n')
+		b.invalidateViews()
+		b.markDirtyFrom(row)
+		b.recordOp(op{kind: opSplitLine, row: row, col: col})
 		return row + 1, 0, nil
 	}
 
 	// Add a char.
-	return row, col + 1, b.data[row].Insert([]rune{char}, col)
+	lineLen, err := b.lineLength(row)
+	if err != nil {
+		return row, col, err
+	}
+	if col > lineLen {
+		return row, col, ErrInvalidPos
+	}
+	if lineLen+1 > b.MaxLineSize() {
+		return row, col, ErrMaxSizeExceeded
+	}
+	pos, err := b.rowStartOffset(row)
+	if err != nil {
+		return row, col, err
+	}
+	b.insertRune(pos+col, char)
+	b.markDirtyFrom(row)
+	b.recordOp(op{kind: opInsert, row: row, col: col, char: char})
+	return row, col + 1, nil
 }
 
 // Delete a char. Returns the new position of the cursor.
@@ -93,35 +253,444 @@ func (b *Buffer) DeleteOne(row, col int) (int, int, error) {
 		if row == 0 {
 			return row, col, ErrInvalidPos
 		}
-		if b.data[row-1].Size()+b.data[row].Size() > b.MaxLineSize() {
+		prevLen, err := b.lineLength(row - 1)
+		if err != nil {
+			return row, col, err
+		}
+		curLen, err := b.lineLength(row)
+		if err != nil {
+			return row, col, err
+		}
+		if prevLen+curLen > b.MaxLineSize() {
 			// Max size exceeded.
 			return row, col, ErrMaxSizeExceeded
 		}
+		pos, err := b.rowStartOffset(row)
+		if err != nil {
+			return row, col, err
+		}
 
-		origData := make([]*BufferLine, len(b.data))
-		copy(origData, b.data)
-		b.data = make([]*BufferLine, len(b.data)-1)
+		// Join the lines by deleting the newline that separates them.
+		if err := b.deleteRune(pos - 1); err != nil {
+			return row, col, err
+		}
+		b.invalidateViews()
+		b.markDirtyFrom(row - 1)
+		b.recordOp(op{kind: opJoinLine, row: row, col: col, arg: prevLen})
+		return row - 1, prevLen, nil
+	}
 
-		// Copy over the lines.
-		copy(b.data[:row-1], origData[:row-1])
-		copy(b.data[row-1:], origData[row:])
+	// Delete a char.
+	lineLen, err := b.lineLength(row)
+	if err != nil {
+		return row, col, err
+	}
+	if col > lineLen {
+		return row, col, ErrInvalidPos
+	}
+	deleted := b.lineData(row)[col-1]
+	pos, err := b.rowStartOffset(row)
+	if err != nil {
+		return row, col, err
+	}
+	if err := b.deleteRune(pos + col - 1); err != nil {
+		return row, col, err
+	}
+	b.markDirtyFrom(row)
+	b.recordOp(op{kind: opDelete, row: row, col: col, char: deleted})
+	return row, col - 1, nil
+}
 
-		// Join the lines.
-		b.data[row-1].data = append(origData[row-1].data, origData[row].data...)
+// opKind identifies what an undo/redo op did to the buffer.
+type opKind int
+
+const (
+	opInsert opKind = iota
+	opDelete
+	opSplitLine
+	opJoinLine
+)
+
+// op is the reversible record of a single InsertOne/DeleteOne call. row/col
+// are the cursor position passed to that call, i.e. the position to restore
+// when the op is undone. char holds the inserted/deleted rune for
+// opInsert/opDelete; arg holds the previous line's length for opJoinLine, so
+// undoing it knows where to re-split.
+type op struct {
+	kind opKind
+	row  int
+	col  int
+	char rune
+	arg  int
+}
 
-		// Return.
-		return row - 1, len(origData[row-1].data), nil
+// transaction is a run of ops undone/redone together as one step.
+type transaction []op
+
+// undoGroupTimeout is the time gap after which a new op starts its own
+// transaction instead of joining the in-progress one, even if it would
+// otherwise be adjacent (e.g. resuming typing after a pause).
+const undoGroupTimeout = 700 * time.Millisecond
+
+// BeginGroup starts an explicit transaction: every op recorded before the
+// matching EndGroup is merged into one undo/redo step, regardless of
+// adjacency or the auto-grouping timeout. Calls may nest; only the
+// outermost EndGroup flushes the transaction.
+func (b *Buffer) BeginGroup() {
+	if b.groupDepth == 0 {
+		b.flushGroup()
 	}
+	b.groupDepth++
+}
 
-	// Delete a char.
-	return row, col - 1, b.data[row].Delete(1, col)
+// EndGroup closes a transaction opened with BeginGroup.
+func (b *Buffer) EndGroup() {
+	if b.groupDepth == 0 {
+		return
+	}
+	b.groupDepth--
+	if b.groupDepth == 0 {
+		b.flushGroup()
+	}
+}
+
+// recordOp appends o to the in-progress transaction, starting a new one if
+// o doesn't belong with what's already there, and clears the redo stack
+// since o is a fresh edit rather than a replayed one.
+func (b *Buffer) recordOp(o op) {
+	if b.applying {
+		// Undo/Redo are replaying ops through InsertOne/DeleteOne; don't
+		// treat the replay itself as a new edit.
+		return
+	}
+	b.redoStack = nil
+
+	if b.groupDepth == 0 && len(b.curTxn) > 0 && !opsAdjacent(b.curTxn[len(b.curTxn)-1], o, b.lastOpAt) {
+		b.flushGroup()
+	}
+	b.curTxn = append(b.curTxn, o)
+	b.lastOpAt = time.Now()
+}
+
+// flushGroup commits the in-progress transaction to the undo stack.
+func (b *Buffer) flushGroup() {
+	if len(b.curTxn) == 0 {
+		return
+	}
+	b.undoStack = append(b.undoStack, b.curTxn)
+	b.curTxn = nil
+}
+
+// opsAdjacent reports whether next can be merged into the same transaction
+// as last: same kind, immediately following position, within the grouping
+// timeout. Structural ops (line split/join) never merge, so pressing Enter
+// or Backspace-at-col-0 always ends up as its own step.
+func opsAdjacent(last, next op, lastAt time.Time) bool {
+	if time.Since(lastAt) > undoGroupTimeout {
+		return false
+	}
+	if next.kind != last.kind || next.row != last.row {
+		return false
+	}
+	switch next.kind {
+	case opInsert:
+		return next.col == last.col+1
+	case opDelete:
+		return next.col == last.col-1
+	default:
+		return false
+	}
+}
+
+// Undo reverses the most recently committed transaction and returns the
+// cursor position to restore (where the user was when the transaction
+// started). Returns ErrInvalidPos if there is nothing to undo.
+func (b *Buffer) Undo() (int, int, error) {
+	b.flushGroup()
+	if len(b.undoStack) == 0 {
+		return 0, 0, ErrInvalidPos
+	}
+	txn := b.undoStack[len(b.undoStack)-1]
+	b.undoStack = b.undoStack[:len(b.undoStack)-1]
+
+	b.applying = true
+	defer func() { b.applying = false }()
+
+	row, col := 0, 0
+	for i := len(txn) - 1; i >= 0; i-- {
+		o := txn[i]
+		var err error
+		switch o.kind {
+		case opInsert:
+			row, col, err = b.DeleteOne(o.row, o.col+1)
+		case opDelete:
+			row, col, err = b.InsertOne(o.char, o.row, o.col-1)
+		case opSplitLine:
+			row, col, err = b.DeleteOne(o.row+1, 0)
+		case opJoinLine:
+			row, col, err = b.InsertOne('\n', o.row-1, o.arg)
+		}
+		if err != nil {
+			return row, col, err
+		}
+	}
+	b.redoStack = append(b.redoStack, txn)
+	return row, col, nil
+}
+
+// Redo reapplies the most recently undone transaction and returns the
+// cursor position after it (where the user was right after making the
+// edit). Returns ErrInvalidPos if there is nothing to redo.
+func (b *Buffer) Redo() (int, int, error) {
+	if len(b.redoStack) == 0 {
+		return 0, 0, ErrInvalidPos
+	}
+	txn := b.redoStack[len(b.redoStack)-1]
+	b.redoStack = b.redoStack[:len(b.redoStack)-1]
+
+	b.applying = true
+	defer func() { b.applying = false }()
+
+	row, col := 0, 0
+	for _, o := range txn {
+		var err error
+		switch o.kind {
+		case opInsert:
+			row, col, err = b.InsertOne(o.char, o.row, o.col)
+		case opDelete:
+			row, col, err = b.DeleteOne(o.row, o.col)
+		case opSplitLine:
+			row, col, err = b.InsertOne('\n', o.row, o.col)
+		case opJoinLine:
+			row, col, err = b.DeleteOne(o.row, o.col)
+		}
+		if err != nil {
+			return row, col, err
+		}
+	}
+	b.undoStack = append(b.undoStack, txn)
+	return row, col, nil
+}
+
+// invalidateViews drops the cached row views; the next Data() call rebuilds
+// them against the current piece table.
+func (b *Buffer) invalidateViews() {
+	b.lineViews = nil
+}
+
+// findNewlines returns the sorted indices of every '\n' in data.
+func findNewlines(data []rune) []int {
+	newlines := make([]int, 0)
+	for i, r := range data {
+		if r == '\n' {
+			newlines = append(newlines, i)
+		}
+	}
+	return newlines
+}
+
+// pieceNewlineCount returns the number of newlines within p's range.
+func (b *Buffer) pieceNewlineCount(p piece) int {
+	newlines := b.originalNewlines
+	if p.source == sourceAdd {
+		newlines = b.addNewlines
+	}
+	lo := sort.Search(len(newlines), func(i int) bool { return newlines[i] >= p.start })
+	hi := sort.Search(len(newlines), func(i int) bool { return newlines[i] >= p.start+p.length })
+	return hi - lo
+}
+
+// nthNewline returns the absolute store offset of the n-th (0-indexed)
+// newline inside p's range.
+func (b *Buffer) nthNewline(p piece, n int) (int, error) {
+	newlines := b.originalNewlines
+	if p.source == sourceAdd {
+		newlines = b.addNewlines
+	}
+	lo := sort.Search(len(newlines), func(i int) bool { return newlines[i] >= p.start })
+	idx := lo + n
+	if idx >= len(newlines) || newlines[idx] >= p.start+p.length {
+		return 0, ErrInvalidPos
+	}
+	return newlines[idx], nil
+}
+
+// rowStartOffset returns the logical rune offset of the first rune of row.
+// row may equal Size(), meaning the (not yet existing) row right after the
+// end of the document.
+func (b *Buffer) rowStartOffset(row int) (int, error) {
+	if row == 0 {
+		return 0, nil
+	}
+	if b.tree == nil || row > b.Size() {
+		return 0, ErrInvalidPos
+	}
+
+	// Find the piece holding the newline that starts this row: the row-th
+	// newline overall (1-indexed).
+	node, startOffset, nlBefore := locateByNewlineRank(b.tree, row)
+	if node == nil {
+		return subRunes(b.tree), nil
+	}
+	within := row - nlBefore - 1
+	nlOffset, err := b.nthNewline(node.piece, within)
+	if err != nil {
+		return 0, err
+	}
+	return startOffset + (nlOffset - node.piece.start) + 1, nil
+}
+
+// lineLength returns the number of runes on row, not counting its newline.
+func (b *Buffer) lineLength(row int) (int, error) {
+	if row >= b.Size() {
+		return 0, ErrInvalidPos
+	}
+	start, err := b.rowStartOffset(row)
+	if err != nil {
+		return 0, err
+	}
+	end, err := b.rowStartOffset(row + 1)
+	if err != nil {
+		return 0, err
+	}
+	if row == b.Size()-1 {
+		return end - start, nil
+	}
+	return end - start - 1, nil
 }
 
-// Buffer line struct.
+// lineData materializes the runes on row by walking every piece it spans.
+func (b *Buffer) lineData(row int) []rune {
+	start, err := b.rowStartOffset(row)
+	if err != nil {
+		return nil
+	}
+	length, err := b.lineLength(row)
+	if err != nil {
+		return nil
+	}
+
+	out := make([]rune, 0, length)
+	it, localStart := newPieceIter(b.tree, start)
+	remaining := length
+	for remaining > 0 {
+		p, ok := it.next()
+		if !ok {
+			break
+		}
+		store := b.original
+		if p.source == sourceAdd {
+			store = b.add
+		}
+		take := p.length - localStart
+		if take > remaining {
+			take = remaining
+		}
+		out = append(out, store[p.start+localStart:p.start+localStart+take]...)
+		remaining -= take
+		localStart = 0
+	}
+	return out
+}
+
+// insertRune inserts a single rune at logical rune offset pos.
+func (b *Buffer) insertRune(pos int, r rune) {
+	b.version++
+	b.dirty = true
+	addStart := len(b.add)
+	b.add = append(b.add, r)
+	nl := 0
+	if r == '\n' {
+		b.addNewlines = append(b.addNewlines, addStart)
+		nl = 1
+	}
+	newPiece := piece{source: sourceAdd, start: addStart, length: 1}
+
+	if b.tree == nil {
+		b.tree = newPieceNode(newPiece, nl)
+		return
+	}
+
+	node, startOffset, rank := b.pieceAtOffset(pos)
+	p := node.piece
+	localPos := pos - startOffset
+
+	// Fast path: typing right after the previous edit just grows it in
+	// place, so sequential typing doesn't fragment into one piece per rune.
+	// The previous edit's piece ends at pos: it is the piece found when pos
+	// is the end of the document, and otherwise the piece holding pos-1.
+	if localPos == p.length && p.source == sourceAdd && p.start+p.length == addStart {
+		grown := piece{source: sourceAdd, start: p.start, length: p.length + 1}
+		b.replacePieceAt(rank, []pieceInsert{{grown, node.ownNL + nl}})
+		return
+	}
+	if localPos == 0 && pos > 0 {
+		prev, _, prevRank := locateByOffset(b.tree, pos-1)
+		if prev != nil && prev.piece.source == sourceAdd && prev.piece.start+prev.piece.length == addStart {
+			grown := piece{source: sourceAdd, start: prev.piece.start, length: prev.piece.length + 1}
+			b.replacePieceAt(prevRank, []pieceInsert{{grown, prev.ownNL + nl}})
+			return
+		}
+	}
+
+	var replacement []pieceInsert
+	switch {
+	case localPos == 0:
+		replacement = []pieceInsert{{newPiece, nl}, {p, node.ownNL}}
+	case localPos == p.length:
+		replacement = []pieceInsert{{p, node.ownNL}, {newPiece, nl}}
+	default:
+		left := piece{source: p.source, start: p.start, length: localPos}
+		right := piece{source: p.source, start: p.start + localPos, length: p.length - localPos}
+		leftNL := b.pieceNewlineCount(left)
+		replacement = []pieceInsert{{left, leftNL}, {newPiece, nl}, {right, node.ownNL - leftNL}}
+	}
+	b.replacePieceAt(rank, replacement)
+}
+
+// deleteRune removes the single rune at logical rune offset pos.
+func (b *Buffer) deleteRune(pos int) error {
+	node, startOffset, rank := b.pieceAtOffset(pos)
+	p := node.piece
+	localPos := pos - startOffset
+	if localPos >= p.length {
+		return ErrInvalidPos
+	}
+	b.version++
+	b.dirty = true
+
+	var replacement []pieceInsert
+	switch {
+	case p.length == 1:
+		// Keep a zero-length placeholder rather than dropping the piece, so
+		// a buffer never loses its last (possibly empty) line.
+		replacement = []pieceInsert{{piece{source: p.source, start: p.start, length: 0}, 0}}
+	case localPos == 0:
+		np := piece{source: p.source, start: p.start + 1, length: p.length - 1}
+		replacement = []pieceInsert{{np, b.pieceNewlineCount(np)}}
+	case localPos == p.length-1:
+		np := piece{source: p.source, start: p.start, length: p.length - 1}
+		replacement = []pieceInsert{{np, b.pieceNewlineCount(np)}}
+	default:
+		left := piece{source: p.source, start: p.start, length: localPos}
+		right := piece{source: p.source, start: p.start + localPos + 1, length: p.length - localPos - 1}
+		replacement = []pieceInsert{{left, b.pieceNewlineCount(left)}, {right, b.pieceNewlineCount(right)}}
+	}
+	b.replacePieceAt(rank, replacement)
+	return nil
+}
+
+// Buffer line struct. A BufferLine is either a standalone line (built via
+// NewBufferLine, e.g. while an editor loads a file) or a lazy view into a
+// Buffer's piece table (returned from Buffer.Data()); the latter reads
+// through to the owning buffer instead of holding its own data.
 type BufferLine struct {
-	// Internal buffer data.
+	// Internal buffer data. Only valid when owner is nil.
 	maxSize int
 	data    []rune
+
+	owner *Buffer
+	row   int
 }
 
 // Create a new buffer line.
@@ -139,16 +708,30 @@ func (b *BufferLine) MaxSize() int {
 
 // Get line size.
 func (b *BufferLine) Size() int {
+	if b.owner != nil {
+		n, err := b.owner.lineLength(b.row)
+		if err != nil {
+			return 0
+		}
+		return n
+	}
 	return len(b.data)
 }
 
 // Get line data.
 func (b *BufferLine) Data() []rune {
+	if b.owner != nil {
+		return b.owner.lineData(b.row)
+	}
 	return b.data
 }
 
 // Insert into the line.
 func (b *BufferLine) Insert(data []rune, pos int) error {
+	if b.owner != nil {
+		// Views into a piece table are read-only; edit through Buffer.
+		return ErrInvalidPos
+	}
 	if pos > b.Size() {
 		return ErrInvalidPos
 	}
@@ -172,6 +755,10 @@ func (b *BufferLine) Insert(data []rune, pos int) error {
 
 // Delete from the line.
 func (b *BufferLine) Delete(num int, pos int) error {
+	if b.owner != nil {
+		// Views into a piece table are read-only; edit through Buffer.
+		return ErrInvalidPos
+	}
 	if pos > b.Size() {
 		return ErrInvalidPos
 	}
@@ -0,0 +1,120 @@
+// buffer/buffer_bench_test.go
+// Benchmarks proving edits stay cheap regardless of file size.
+
+package buffer
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// buildLargeBuffer loads a large buffer the way editor.Init would: one
+// BufferLine per source line, then handed to SetData.
+func buildLargeBuffer(numLines int) *Buffer {
+	lines := make([]*BufferLine, numLines)
+	for i := range lines {
+		l := NewBufferLine(1 << 16)
+		l.Insert([]rune("the quick brown fox jumps over the lazy dog"), 0)
+		lines[i] = l
+	}
+	buf := NewBuffer(1<<30, 1<<20)
+	buf.SetData(lines)
+	return buf
+}
+
+// BenchmarkInsertScattered inserts characters at random rows/columns
+// throughout a 100k-line buffer, standing in for the "insert 1M characters
+// scattered through a large file" scenario the piece table is meant to fix.
+func BenchmarkInsertScattered(b *testing.B) {
+	buf := buildLargeBuffer(100000)
+	rnd := rand.New(rand.NewSource(1))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		row := rnd.Intn(buf.Size())
+		lineLen := buf.Data()[row].Size()
+		col := 0
+		if lineLen > 0 {
+			col = rnd.Intn(lineLen)
+		}
+		if _, _, err := buf.InsertOne('x', row, col); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInsertSequential types at a single advancing cursor, the common
+// case the fast-path piece merge is meant to keep at O(1) per keystroke.
+func BenchmarkInsertSequential(b *testing.B) {
+	buf := buildLargeBuffer(100000)
+	row, col := 0, 0
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var err error
+		row, col, err = buf.InsertOne('x', row, col)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkInsertScatteredAfterPriorEdits checks that a scattered insert's
+// cost doesn't grow with how many edits the buffer has already taken. Each
+// sub-benchmark primes the buffer with a different number of scattered
+// inserts before timing one more batch, so a regression to the flat
+// prefix-sum array (whose rebuild cost grows with edit history) shows up as
+// ns/op increasing across the table instead of staying flat.
+func BenchmarkInsertScatteredAfterPriorEdits(b *testing.B) {
+	for _, prior := range []int{0, 1000, 5000} {
+		b.Run(fmt.Sprintf("prior=%d", prior), func(b *testing.B) {
+			buf := buildLargeBuffer(200000)
+			rnd := rand.New(rand.NewSource(3))
+			for i := 0; i < prior; i++ {
+				row := rnd.Intn(buf.Size())
+				lineLen := buf.Data()[row].Size()
+				col := 0
+				if lineLen > 0 {
+					col = rnd.Intn(lineLen)
+				}
+				if _, _, err := buf.InsertOne('x', row, col); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				row := rnd.Intn(buf.Size())
+				lineLen := buf.Data()[row].Size()
+				col := 0
+				if lineLen > 0 {
+					col = rnd.Intn(lineLen)
+				}
+				if _, _, err := buf.InsertOne('x', row, col); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDeleteScattered deletes characters at random positions, exercising
+// the split/shrink path of deleteRune across a large document.
+func BenchmarkDeleteScattered(b *testing.B) {
+	buf := buildLargeBuffer(100000)
+	rnd := rand.New(rand.NewSource(2))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		row := rnd.Intn(buf.Size())
+		lineLen := buf.Data()[row].Size()
+		if lineLen == 0 {
+			continue
+		}
+		col := rnd.Intn(lineLen) + 1
+		if _, _, err := buf.DeleteOne(row, col); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// buffer/buffer_test.go
+
+package buffer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lineStrings materializes every row of buf as a string, for comparing
+// against a plain []string reference model.
+func lineStrings(buf *Buffer) []string {
+	out := make([]string, buf.Size())
+	for i, l := range buf.Data() {
+		out[i] = string(l.Data())
+	}
+	return out
+}
+
+// TestInsertDeleteMatchesNaiveModel inserts and deletes runes at scattered
+// positions and checks the piece table against a plain []string model kept in
+// lockstep, the way a reference implementation would.
+func TestInsertDeleteMatchesNaiveModel(t *testing.T) {
+	buf := buildLargeBuffer(50)
+	var model []string
+	for _, l := range lineStrings(buf) {
+		model = append(model, l)
+	}
+
+	rnd := rand.New(rand.NewSource(7))
+	for i := 0; i < 2000; i++ {
+		row := rnd.Intn(len(model))
+		col := rnd.Intn(len(model[row]) + 1)
+
+		if rnd.Intn(2) == 0 || len(model[row]) == 0 {
+			ch := rune('a' + rnd.Intn(26))
+			if _, _, err := buf.InsertOne(ch, row, col); err != nil {
+				t.Fatalf("iter %d: InsertOne(%q, %d, %d): %v", i, ch, row, col, err)
+			}
+			model[row] = model[row][:col] + string(ch) + model[row][col:]
+			continue
+		}
+
+		if col == 0 {
+			if row == 0 {
+				continue
+			}
+			if _, _, err := buf.DeleteOne(row, col); err != nil {
+				t.Fatalf("iter %d: DeleteOne(%d, %d): %v", i, row, col, err)
+			}
+			joined := model[row-1] + model[row]
+			model = append(model[:row-1], append([]string{joined}, model[row+1:]...)...)
+			continue
+		}
+
+		if _, _, err := buf.DeleteOne(row, col); err != nil {
+			t.Fatalf("iter %d: DeleteOne(%d, %d): %v", i, row, col, err)
+		}
+		model[row] = model[row][:col-1] + model[row][col:]
+	}
+
+	got := lineStrings(buf)
+	if len(got) != len(model) {
+		t.Fatalf("line count: got %d, want %d", len(got), len(model))
+	}
+	for i := range model {
+		if got[i] != model[i] {
+			t.Fatalf("row %d: got %q, want %q", i, got[i], model[i])
+		}
+	}
+}
+
+// TestInsertNewlineSplitsRow checks that inserting '\n' mid-line splits it
+// into two rows at the right point.
+func TestInsertNewlineSplitsRow(t *testing.T) {
+	buf := NewBuffer(1<<30, 1<<20)
+	l := NewBufferLine(1 << 16)
+	l.Insert([]rune("helloworld"), 0)
+	buf.SetData([]*BufferLine{l})
+
+	if _, _, err := buf.InsertOne('\n', 0, 5); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	if buf.Size() != 2 {
+		t.Fatalf("expected 2 rows, got %d", buf.Size())
+	}
+	if got := string(buf.Data()[0].Data()); got != "hello" {
+		t.Fatalf("row 0: got %q", got)
+	}
+	if got := string(buf.Data()[1].Data()); got != "world" {
+		t.Fatalf("row 1: got %q", got)
+	}
+}
+
+// TestDeleteAtColZeroJoinsRows checks that DeleteOne at col 0 merges the
+// current row into the previous one.
+func TestDeleteAtColZeroJoinsRows(t *testing.T) {
+	buf := NewBuffer(1<<30, 1<<20)
+	a := NewBufferLine(1 << 16)
+	a.Insert([]rune("hello"), 0)
+	b := NewBufferLine(1 << 16)
+	b.Insert([]rune("world"), 0)
+	buf.SetData([]*BufferLine{a, b})
+
+	row, col, err := buf.DeleteOne(1, 0)
+	if err != nil {
+		t.Fatalf("DeleteOne: %v", err)
+	}
+	if row != 0 || col != 5 {
+		t.Fatalf("cursor after join: got (%d,%d), want (0,5)", row, col)
+	}
+	if buf.Size() != 1 {
+		t.Fatalf("expected 1 row, got %d", buf.Size())
+	}
+	if got := string(buf.Data()[0].Data()); got != "helloworld" {
+		t.Fatalf("row 0: got %q", got)
+	}
+}
+
+func TestLineStringsHelperSanity(t *testing.T) {
+	buf := buildLargeBuffer(3)
+	want := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 2) + "the quick brown fox jumps over the lazy dog"
+	got := strings.Join(lineStrings(buf), "\n")
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
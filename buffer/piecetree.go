@@ -0,0 +1,240 @@
+// buffer/piecetree.go
+// A treap ordering the document's pieces by sequence position (an implicit
+// key, not an explicit value), so both "find the piece at rune offset N"
+// and "find the piece containing line N" are O(log n) tree descents, and
+// splicing pieces in or out at an edit point is an O(log n) split/merge
+// instead of rebuilding a flat prefix-sum array from that point onward.
+
+package buffer
+
+import "math/rand"
+
+// pieceNode is one node of the piece treap.
+type pieceNode struct {
+	piece piece
+	ownNL int // newlines within this node's own piece
+
+	left, right *pieceNode
+	priority    uint32
+
+	// Subtree aggregates, including this node.
+	count int // piece count
+	runes int // total rune length
+	nl    int // total newline count
+}
+
+func subCount(n *pieceNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.count
+}
+
+func subRunes(n *pieceNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.runes
+}
+
+func subNL(n *pieceNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.nl
+}
+
+// pull recomputes n's subtree aggregates from its own piece and its
+// children's aggregates.
+func pull(n *pieceNode) {
+	n.count = 1 + subCount(n.left) + subCount(n.right)
+	n.runes = n.piece.length + subRunes(n.left) + subRunes(n.right)
+	n.nl = n.ownNL + subNL(n.left) + subNL(n.right)
+}
+
+// newPieceNode builds a single-node treap for p, whose range contains ownNL
+// newlines.
+func newPieceNode(p piece, ownNL int) *pieceNode {
+	return &pieceNode{
+		piece:    p,
+		ownNL:    ownNL,
+		priority: rand.Uint32(),
+		count:    1,
+		runes:    p.length,
+		nl:       ownNL,
+	}
+}
+
+// split divides n into the first k pieces (in sequence order) and the rest.
+func split(n *pieceNode, k int) (*pieceNode, *pieceNode) {
+	if n == nil {
+		return nil, nil
+	}
+	lc := subCount(n.left)
+	if k <= lc {
+		l, r := split(n.left, k)
+		n.left = r
+		pull(n)
+		return l, n
+	}
+	l, r := split(n.right, k-lc-1)
+	n.right = l
+	pull(n)
+	return n, r
+}
+
+// merge concatenates l and r, where every piece in l precedes every piece
+// in r.
+func merge(l, r *pieceNode) *pieceNode {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.priority > r.priority {
+		l.right = merge(l.right, r)
+		pull(l)
+		return l
+	}
+	r.left = merge(l, r.left)
+	pull(r)
+	return r
+}
+
+// rightmost returns the last piece in sequence order.
+func rightmost(n *pieceNode) *pieceNode {
+	if n == nil {
+		return nil
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+// locateByOffset descends the treap to find the piece containing logical
+// rune offset pos, returning it along with the rune offset its range
+// starts at and its rank (position among all pieces). Returns a nil node
+// if pos is at or past the end of the document.
+func locateByOffset(n *pieceNode, pos int) (node *pieceNode, startOffset, rank int) {
+	for n != nil {
+		leftLen := subRunes(n.left)
+		if pos < leftLen {
+			n = n.left
+			continue
+		}
+		pos -= leftLen
+		leftCount := subCount(n.left)
+		if pos < n.piece.length {
+			return n, startOffset + leftLen, rank + leftCount
+		}
+		pos -= n.piece.length
+		startOffset += leftLen + n.piece.length
+		rank += leftCount + 1
+		n = n.right
+	}
+	return nil, startOffset, rank
+}
+
+// locateByNewlineRank descends the treap to find the piece containing the
+// row-th newline overall (the one that starts row's text), returning it
+// along with the rune offset and newline count that precede it. Returns a
+// nil node once row exceeds the total newline count (end of document).
+func locateByNewlineRank(n *pieceNode, row int) (node *pieceNode, startOffset, nlBefore int) {
+	for n != nil {
+		leftLen := subRunes(n.left)
+		leftNL := subNL(n.left)
+		if row <= leftNL {
+			n = n.left
+			continue
+		}
+		row -= leftNL
+		if row <= n.ownNL {
+			return n, startOffset + leftLen, nlBefore + leftNL
+		}
+		row -= n.ownNL
+		startOffset += leftLen + n.piece.length
+		nlBefore += leftNL + n.ownNL
+		n = n.right
+	}
+	return nil, startOffset, nlBefore
+}
+
+// pieceIter walks pieces in sequence order starting from a given node,
+// using an explicit ancestor stack (standard BST successor iteration) so
+// materializing one line doesn't require flattening the whole tree.
+type pieceIter struct {
+	stack []*pieceNode
+}
+
+// newPieceIter seeds an iterator at the piece containing rune offset
+// startOffset, also returning that piece's local offset within itself.
+func newPieceIter(root *pieceNode, startOffset int) (*pieceIter, int) {
+	it := &pieceIter{}
+	n := root
+	pos := startOffset
+	for n != nil {
+		leftLen := subRunes(n.left)
+		if pos < leftLen {
+			it.stack = append(it.stack, n)
+			n = n.left
+			continue
+		}
+		pos -= leftLen
+		if pos < n.piece.length {
+			it.stack = append(it.stack, n)
+			return it, pos
+		}
+		pos -= n.piece.length
+		n = n.right
+	}
+	return it, 0
+}
+
+// next returns the next piece in sequence order, or ok=false once the
+// iterator is exhausted.
+func (it *pieceIter) next() (p piece, ok bool) {
+	if len(it.stack) == 0 {
+		return piece{}, false
+	}
+	n := it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	for m := n.right; m != nil; m = m.left {
+		it.stack = append(it.stack, m)
+	}
+	return n.piece, true
+}
+
+// pieceInsert is a piece being spliced into the treap, paired with its
+// newline count (the caller already knows this from splitting a piece it
+// had the newline index for, so there's no reason to recompute it).
+type pieceInsert struct {
+	piece piece
+	nl    int
+}
+
+// pieceAtOffset finds the piece containing rune offset pos, clamping to
+// the last piece when pos is exactly at the end of the document (the
+// position a caller inserts/deletes at when appending).
+func (b *Buffer) pieceAtOffset(pos int) (node *pieceNode, startOffset, rank int) {
+	node, startOffset, rank = locateByOffset(b.tree, pos)
+	if node == nil {
+		node = rightmost(b.tree)
+		rank = subCount(b.tree) - 1
+		startOffset = subRunes(b.tree) - node.piece.length
+	}
+	return
+}
+
+// replacePieceAt splices replacements in where the piece at rank used to
+// be, an O(log n) split/merge regardless of how many pieces exist.
+func (b *Buffer) replacePieceAt(rank int, replacements []pieceInsert) {
+	left, rest := split(b.tree, rank)
+	_, right := split(rest, 1)
+	var mid *pieceNode
+	for _, r := range replacements {
+		mid = merge(mid, newPieceNode(r.piece, r.nl))
+	}
+	b.tree = merge(merge(left, mid), right)
+}
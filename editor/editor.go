@@ -8,14 +8,26 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/cubeflix/edit/buffer"
+	"github.com/cubeflix/edit/render"
+	"github.com/cubeflix/edit/search"
+	"github.com/cubeflix/edit/syntax"
 	"github.com/gdamore/tcell"
 )
 
 const (
 	MaxBufferSize = 1e5
 	MaxLineSize   = 1e5
+
+	// DefaultQuitTimes is how many times Escape/Ctrl-Q must be pressed in a
+	// row to quit with unsaved changes.
+	DefaultQuitTimes = 3
+
+	// messageTimeout is how long a message-bar message stays visible.
+	messageTimeout = 5 * time.Second
 )
 
 // Editor struct.
@@ -44,6 +56,46 @@ type Editor struct {
 
 	// File buffer.
 	buffer *buffer.Buffer
+
+	// frame is the diffing cell grid Render paints into; only cells that
+	// changed since the last frame are sent to the screen.
+	frame *render.Buffer
+
+	// Syntax highlighting. highlights is nil if no built-in definition
+	// matched the file's extension.
+	highlights *syntax.Highlights
+	theme      syntax.Theme
+
+	// Incremental search. Non-nil while a search session (pattern entry or
+	// match navigation) is active; see search.go.
+	search       *search.Search
+	searchTyping bool
+	searchOrigY  int
+	searchOrigX  int
+
+	// Key bindings. pendingChord is set while waiting for the second key of
+	// a chord (e.g. after Ctrl-X); pendingCount accumulates the digits of a
+	// numeric prefix argument. A keymap with SelfInsert disabled (e.g. a
+	// vim-like normal mode) can start one with a bare digit, since an
+	// unbound digit has nothing else to do there; a self-inserting keymap
+	// (the default) requires Alt-<digit> instead, so plain digit typing is
+	// untouched. Either way, once a prefix is pending, further plain digits
+	// continue it (e.g. "12" then Down moves down 12 lines).
+	keymap       *Keymap
+	pendingChord *Keymap
+	pendingCount string
+	countPending bool
+
+	// Quit confirmation. QuitTimes is how many consecutive quit presses a
+	// dirty buffer requires before Exit actually runs; quitRemaining counts
+	// down from it and resets whenever any other command runs.
+	QuitTimes     int
+	quitRemaining int
+
+	// Transient message-bar message (e.g. Save's result), along with when
+	// it was set so Render can let it expire.
+	message   string
+	messageAt time.Time
 }
 
 // Create a new editor.
@@ -56,12 +108,26 @@ func NewEditor(file string) (*Editor, error) {
 
 	// Return.
 	return &Editor{
-		screen: screen,
-		file:   file,
-		buffer: buffer.NewBuffer(MaxBufferSize, MaxLineSize),
+		screen:    screen,
+		file:      file,
+		buffer:    buffer.NewBuffer(MaxBufferSize, MaxLineSize),
+		keymap:    DefaultKeymap(),
+		QuitTimes: DefaultQuitTimes,
+		theme:     syntax.DefaultTheme(),
 	}, nil
 }
 
+// SetKeymap replaces the editor's active keymap wholesale, e.g. to switch
+// to a vim-like or emacs-like binding set.
+func (e *Editor) SetKeymap(k *Keymap) {
+	e.keymap = k
+}
+
+// BindKey adds or replaces a single binding in the editor's active keymap.
+func (e *Editor) BindKey(evt KeyEvent, cmd Command) {
+	e.keymap.Bind(evt, cmd)
+}
+
 // Initialize the editor.
 func (e *Editor) Init() error {
 	// Initialize the screen.
@@ -109,8 +175,14 @@ func (e *Editor) Init() error {
 		e.buffer.SetData(lines)
 	}
 
+	// Pick a syntax highlighter by file extension, if one is built in.
+	if hl, ok := syntax.ForFile(e.file); ok {
+		e.highlights = syntax.NewHighlights(e.buffer, hl)
+	}
+
 	// Get the screen size.
 	e.width, e.height = e.screen.Size()
+	e.frame = render.NewBuffer(e.width, e.height)
 
 	e.screen.Clear()
 
@@ -127,178 +199,183 @@ func (e *Editor) HandleEvents() {
 
 		switch event := event.(type) {
 		case *tcell.EventKey:
-			e.handleKeyPress(event)
+			if e.handleKeyPress(event) {
+				e.Render()
+			}
 		case *tcell.EventResize:
 			e.width, e.height = event.Size()
+			e.frame.Resize(e.width, e.height)
 			e.Render()
 		}
 	}
 }
 
-// Handle a key press.
+// Handle a key press. Lookups go through the active keymap; an unbound
+// printable rune self-inserts, mirroring the editor's original hardcoded
+// behavior. See keymap.go for numeric prefix arguments and chords.
 func (e *Editor) handleKeyPress(event *tcell.EventKey) (render bool) {
-	if event.Key() == tcell.KeyEscape {
-		// Exit.
-		e.Exit()
-		return false
+	if e.search != nil {
+		return e.handleSearchKey(event)
 	}
-	defer func() {
-		if render {
-			e.Render()
-		}
-	}()
-	if event.Key() == tcell.KeyDown {
-		// Down.
-		if e.cursorY >= e.buffer.Size()-1 {
-			e.screen.Beep()
-			return true
-		}
-		e.cursorY += 1
-
-		// If the next line is too short, move the cursor X.
-		newSize := e.buffer.Data()[e.cursorY].Size()
-		if e.cursorX >= newSize {
-			e.cursorX = newSize
-			if e.cursorX < e.left {
-				// Out of viewing area.
-				e.left = e.cursorX
-			}
-		}
-	} else if event.Key() == tcell.KeyUp {
-		// Up.
-		if e.cursorY == 0 {
-			e.screen.Beep()
-			return true
-		}
-		e.cursorY -= 1
-
-		// If the next line is too short, move the cursor X.
-		newSize := e.buffer.Data()[e.cursorY].Size()
-		if e.cursorX >= newSize {
-			e.cursorX = newSize
-			if e.cursorX < e.left {
-				// Out of viewing area.
-				e.left = e.cursorX
-			}
-		}
-	} else if event.Key() == tcell.KeyLeft {
-		// Left.
-		if e.cursorX == 0 {
-			if e.cursorY == 0 {
-				e.screen.Beep()
-				return true
-			}
-			e.cursorY -= 1
-			e.cursorX = e.buffer.Data()[e.cursorY].Size()
-			if e.cursorX >= e.left+e.width {
-				// Out of viewing area.
-				e.left = e.cursorX - e.width
-			}
-			return true
-		}
-		e.cursorX -= 1
-	} else if event.Key() == tcell.KeyRight {
-		// Right.
-		if e.cursorX >= e.buffer.Data()[e.cursorY].Size() {
-			if e.cursorY >= e.buffer.Size()-1 {
-				e.screen.Beep()
-				return true
-			}
-			e.cursorY += 1
-			e.cursorX = 0
-			e.left = 0
-			return true
-		}
-		e.cursorX += 1
-	} else if event.Key() == tcell.KeyEnter {
-		// Insert new line.
-		var err error
-		e.cursorY, e.cursorX, err = e.buffer.InsertOne('\n', e.cursorY, e.cursorX)
-		if err != nil {
-			e.screen.Beep()
-			return true
-		}
-	} else if event.Key() == tcell.KeyBackspace {
-		// Backspace.
-		var err error
-		e.cursorY, e.cursorX, err = e.buffer.DeleteOne(e.cursorY, e.cursorX)
-		if err != nil {
+
+	evt := keyEventFrom(event)
+
+	// Mid-chord: the next key either completes it or cancels it.
+	if e.pendingChord != nil {
+		chord := e.pendingChord
+		e.pendingChord = nil
+		b, ok := chord.lookup(evt)
+		if !ok || b.command == nil {
+			e.resetCount()
 			e.screen.Beep()
 			return true
 		}
-	} else if event.Key() == tcell.KeyCtrlS {
-		// Save.
-		err := e.Save()
-		e.Render()
-		if err != nil {
-			style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
-			for i := range err.Error() {
-				e.screen.SetContent(i, e.height-1, rune(err.Error()[i]), nil, style)
-			}
-			e.screen.Beep()
-			e.screen.Sync()
+		return e.dispatch(b.command)
+	}
+
+	if event.Key() == tcell.KeyEscape {
+		if e.countPending {
+			// Cancel the pending numeric prefix.
+			e.resetCount()
 			return false
 		}
-		style := tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
-		for i := range "saved" {
-			e.screen.SetContent(i, e.height-1, rune("saved"[i]), nil, style)
+		return e.attemptQuit()
+	}
+	if event.Key() == tcell.KeyCtrlQ {
+		return e.attemptQuit()
+	}
+
+	if b, ok := e.keymap.lookup(evt); ok {
+		if b.chord != nil {
+			e.pendingChord = b.chord
+			return false
 		}
-		e.screen.Sync()
+		return e.dispatch(b.command)
+	}
+
+	// A digit with no explicit binding starts or continues a numeric prefix
+	// argument instead of self-inserting. In a keymap that doesn't
+	// self-insert, a bare digit has no other job, so it can start the
+	// prefix directly; a self-inserting keymap (the default) only starts
+	// one on Alt-<digit>, so ordinary digit typing keeps working. Either
+	// way, once a prefix is already pending, a following plain digit
+	// continues it.
+	if r := evt.Rune; evt.Key == tcell.KeyRune && r >= '0' && r <= '9' &&
+		(e.countPending || evt.Mod&tcell.ModAlt != 0 || (!e.keymap.SelfInsert && evt.Mod == tcell.ModNone)) {
+		e.countPending = true
+		e.pendingCount += string(r)
 		return false
-	} else {
-		// Insert.
-		var err error
-		e.cursorY, e.cursorX, err = e.buffer.InsertOne(event.Rune(), e.cursorY, e.cursorX)
-		if err != nil {
-			e.screen.Beep()
-			return true
-		}
 	}
+
+	// Fall through to self-insert for any other rune, unless the active
+	// keymap disables it (e.g. a vim-like normal mode, where an unbound
+	// letter should be a no-op instead of typed text).
+	if evt.Key == tcell.KeyRune && e.keymap.SelfInsert {
+		r := evt.Rune
+		return e.dispatch(func(e *Editor, count int) error {
+			return selfInsert(e, count, r)
+		})
+	}
+
+	e.resetCount()
+	e.screen.Beep()
 	return true
 }
 
-// Render the buffer.
-func (e *Editor) Render() error {
-	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
-	e.screen.Clear()
+// dispatch consumes the pending numeric prefix (defaulting to 1) and runs
+// cmd with it. Running any real command resets the quit-confirmation
+// countdown, since that only tracks *consecutive* quit presses.
+func (e *Editor) dispatch(cmd Command) bool {
+	count := e.consumeCount()
+	e.quitRemaining = 0
+	if err := cmd(e, count); err != nil {
+		e.screen.Beep()
+	}
+	return true
+}
 
-	// Calculate the top position.
-	// If the cursor is out of the current viewport, move the viewport.
-	if e.cursorY >= e.top+e.height {
-		e.top += 1
-	} else if e.cursorY < e.top {
-		e.top -= 1
+// consumeCount parses and clears the pending numeric prefix, defaulting to
+// 1 if none was entered (or it didn't parse to a positive number).
+func (e *Editor) consumeCount() int {
+	if !e.countPending {
+		return 1
+	}
+	n, err := strconv.Atoi(e.pendingCount)
+	e.resetCount()
+	if err != nil || n <= 0 {
+		return 1
 	}
+	return n
+}
 
-	// Calculate the left position.
-	// If the cursor is out of the current viewport, move the viewport.
-	if e.cursorX >= e.left+e.width {
-		e.left += 1
-	} else if e.cursorX < e.left {
-		e.left -= 1
+// resetCount clears any in-progress numeric prefix.
+func (e *Editor) resetCount() {
+	e.countPending = false
+	e.pendingCount = ""
+}
+
+// textHeight returns how many rows are available for buffer text, after
+// reserving the status bar and message bar on the last two rows.
+func (e *Editor) textHeight() int {
+	if e.height < 2 {
+		return e.height
 	}
+	return e.height - 2
+}
 
-	// Draw the text.
-	for i := 0; i < e.height; i++ {
-		line := e.top + i
+// Render paints one frame into e.frame, layer by layer (text, search
+// highlights, status line, message line), then flushes only the cells
+// that changed since the last frame.
+func (e *Editor) Render() error {
+	style := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
+	e.frame.Clear()
+
+	vp := viewport{top: e.top, left: e.left, width: e.width, height: e.textHeight()}
+	vp = vp.scroll(e.cursorY, e.cursorX)
+	e.top, e.left = vp.top, vp.left
+
+	// Text layer, with syntax highlighting applied where a span covers a
+	// cell.
+	for i := 0; i < vp.height; i++ {
+		line := vp.top + i
 		if line > e.buffer.Size()-1 {
 			break
 		}
 
 		lineData := e.buffer.Data()[line]
-		if e.left >= lineData.Size() {
+		if vp.left >= lineData.Size() {
 			continue
 		}
-		display := lineData.Data()[e.left:]
+		display := lineData.Data()[vp.left:]
+
+		var spans []syntax.Span
+		if e.highlights != nil {
+			spans = e.highlights.SpansForLine(line)
+		}
+
 		for j := range display {
-			e.screen.SetContent(j, i, display[j], nil, style)
+			col := vp.left + j
+			cellStyle := style
+			for _, sp := range spans {
+				if col >= sp.Start && col < sp.End {
+					cellStyle = e.theme.Style(sp.Style)
+					break
+				}
+			}
+			e.frame.Set(j, i, display[j], cellStyle)
 		}
 	}
 
-	e.screen.ShowCursor(e.cursorX-e.left, e.cursorY-e.top)
+	// Search highlight layer, status line, and message line.
+	e.renderStatusBar()
+	if e.search != nil {
+		e.renderSearch()
+	} else {
+		e.renderMessageBar()
+	}
 
-	// Sync and return.
-	e.screen.Sync()
+	e.screen.ShowCursor(e.cursorX-e.left, e.cursorY-e.top)
+	e.frame.Flush(e.screen)
 	return nil
 }
 
@@ -321,6 +398,8 @@ func (e *Editor) Save() error {
 		}
 	}
 
+	e.buffer.MarkSaved()
+
 	// Return.
 	return nil
 }
@@ -0,0 +1,281 @@
+// editor/keymap.go
+// Pluggable key bindings for the editor, with support for numeric prefix
+// arguments and multi-key chords.
+
+package editor
+
+import "github.com/gdamore/tcell"
+
+// Command is a bound action. count is the numeric prefix argument in
+// effect for this keypress (1 if the user didn't type one).
+type Command func(e *Editor, count int) error
+
+// KeyEvent identifies a single keypress: either a non-rune key (arrows,
+// Enter, Ctrl chords, ...) or a printable rune, plus whatever modifiers
+// were held. It's comparable, so it can key a Keymap's binding map
+// directly.
+type KeyEvent struct {
+	Key  tcell.Key
+	Rune rune
+	Mod  tcell.ModMask
+}
+
+// keyEventFrom normalizes a tcell key event into a KeyEvent.
+func keyEventFrom(event *tcell.EventKey) KeyEvent {
+	evt := KeyEvent{Key: event.Key(), Mod: event.Modifiers()}
+	if evt.Key == tcell.KeyRune {
+		evt.Rune = event.Rune()
+	}
+	return evt
+}
+
+// binding is either a leaf Command or, for chords like Ctrl-X Ctrl-S, a
+// child Keymap that the next keypress is looked up in.
+type binding struct {
+	command Command
+	chord   *Keymap
+}
+
+// Keymap maps key events to commands. It holds no editor state itself, so
+// the same Keymap can be shared or swapped wholesale via Editor.SetKeymap.
+type Keymap struct {
+	bindings map[KeyEvent]binding
+
+	// SelfInsert controls whether a printable rune with no binding in this
+	// keymap falls through to inserting it into the buffer. NewKeymap sets
+	// it true, matching the editor's original behavior; a vim-like normal
+	// mode keymap can set it false so an unbound letter is a no-op instead
+	// of being typed, without having to bind every single printable rune.
+	SelfInsert bool
+}
+
+// NewKeymap creates an empty keymap with self-insertion enabled.
+func NewKeymap() *Keymap {
+	return &Keymap{bindings: make(map[KeyEvent]binding), SelfInsert: true}
+}
+
+// Bind maps evt directly to cmd, replacing any existing binding (including
+// a chord prefix) for evt.
+func (k *Keymap) Bind(evt KeyEvent, cmd Command) {
+	k.bindings[evt] = binding{command: cmd}
+}
+
+// BindChord maps the two-key sequence prefix, then evt, to cmd. Multiple
+// chords sharing a prefix (e.g. Ctrl-X Ctrl-S and Ctrl-X Ctrl-C) can be
+// built up with repeated calls using the same prefix.
+func (k *Keymap) BindChord(prefix, evt KeyEvent, cmd Command) {
+	b, ok := k.bindings[prefix]
+	if !ok || b.chord == nil {
+		b = binding{chord: NewKeymap()}
+		k.bindings[prefix] = b
+	}
+	b.chord.Bind(evt, cmd)
+}
+
+// lookup returns the binding for evt, if any.
+func (k *Keymap) lookup(evt KeyEvent) (binding, bool) {
+	b, ok := k.bindings[evt]
+	return b, ok
+}
+
+// DefaultKeymap returns the keymap mirroring the editor's built-in
+// behavior: arrow movement, Enter/Backspace editing, undo/redo, and save
+// (bound both to Ctrl-S directly and to the Ctrl-X Ctrl-S chord, as an
+// example of chord binding). Printable runes with no binding fall through
+// to self-insertion; see handleKeyPress.
+func DefaultKeymap() *Keymap {
+	k := NewKeymap()
+	k.Bind(KeyEvent{Key: tcell.KeyDown}, cmdDown)
+	k.Bind(KeyEvent{Key: tcell.KeyUp}, cmdUp)
+	k.Bind(KeyEvent{Key: tcell.KeyLeft}, cmdLeft)
+	k.Bind(KeyEvent{Key: tcell.KeyRight}, cmdRight)
+	k.Bind(KeyEvent{Key: tcell.KeyEnter}, cmdEnter)
+	k.Bind(KeyEvent{Key: tcell.KeyBackspace}, cmdBackspace)
+	k.Bind(KeyEvent{Key: tcell.KeyCtrlZ}, cmdUndo)
+	k.Bind(KeyEvent{Key: tcell.KeyCtrlY}, cmdRedo)
+	k.Bind(KeyEvent{Key: tcell.KeyCtrlS}, cmdSave)
+	k.BindChord(KeyEvent{Key: tcell.KeyCtrlX}, KeyEvent{Key: tcell.KeyCtrlS}, cmdSave)
+	k.Bind(KeyEvent{Key: tcell.KeyCtrlF}, cmdStartSearch)
+	k.Bind(KeyEvent{Key: tcell.KeyRune, Rune: '/'}, cmdStartSearch)
+	return k
+}
+
+// cmdDown moves the cursor down count lines, stopping at the last line.
+func cmdDown(e *Editor, count int) error {
+	moved := false
+	for i := 0; i < count; i++ {
+		if e.cursorY >= e.buffer.Size()-1 {
+			break
+		}
+		e.cursorY++
+		moved = true
+
+		// If the next line is too short, move the cursor X.
+		newSize := e.buffer.Data()[e.cursorY].Size()
+		if e.cursorX >= newSize {
+			e.cursorX = newSize
+			if e.cursorX < e.left {
+				// Out of viewing area.
+				e.left = e.cursorX
+			}
+		}
+	}
+	if !moved {
+		e.screen.Beep()
+	}
+	return nil
+}
+
+// cmdUp moves the cursor up count lines, stopping at the first line.
+func cmdUp(e *Editor, count int) error {
+	moved := false
+	for i := 0; i < count; i++ {
+		if e.cursorY == 0 {
+			break
+		}
+		e.cursorY--
+		moved = true
+
+		// If the next line is too short, move the cursor X.
+		newSize := e.buffer.Data()[e.cursorY].Size()
+		if e.cursorX >= newSize {
+			e.cursorX = newSize
+			if e.cursorX < e.left {
+				// Out of viewing area.
+				e.left = e.cursorX
+			}
+		}
+	}
+	if !moved {
+		e.screen.Beep()
+	}
+	return nil
+}
+
+// cmdLeft moves the cursor left count positions, wrapping to the end of
+// the previous line at the start of a line.
+func cmdLeft(e *Editor, count int) error {
+	moved := false
+	for i := 0; i < count; i++ {
+		if e.cursorX == 0 {
+			if e.cursorY == 0 {
+				break
+			}
+			e.cursorY--
+			e.cursorX = e.buffer.Data()[e.cursorY].Size()
+			if e.cursorX >= e.left+e.width {
+				// Out of viewing area.
+				e.left = e.cursorX - e.width
+			}
+			moved = true
+			continue
+		}
+		e.cursorX--
+		moved = true
+	}
+	if !moved {
+		e.screen.Beep()
+	}
+	return nil
+}
+
+// cmdRight moves the cursor right count positions, wrapping to the start
+// of the next line at the end of a line.
+func cmdRight(e *Editor, count int) error {
+	moved := false
+	for i := 0; i < count; i++ {
+		if e.cursorX >= e.buffer.Data()[e.cursorY].Size() {
+			if e.cursorY >= e.buffer.Size()-1 {
+				break
+			}
+			e.cursorY++
+			e.cursorX = 0
+			e.left = 0
+			moved = true
+			continue
+		}
+		e.cursorX++
+		moved = true
+	}
+	if !moved {
+		e.screen.Beep()
+	}
+	return nil
+}
+
+// cmdEnter inserts count newlines at the cursor.
+func cmdEnter(e *Editor, count int) error {
+	for i := 0; i < count; i++ {
+		var err error
+		e.cursorY, e.cursorX, err = e.buffer.InsertOne('\n', e.cursorY, e.cursorX)
+		if err != nil {
+			e.screen.Beep()
+			return nil
+		}
+	}
+	return nil
+}
+
+// cmdBackspace deletes count characters before the cursor.
+func cmdBackspace(e *Editor, count int) error {
+	for i := 0; i < count; i++ {
+		var err error
+		e.cursorY, e.cursorX, err = e.buffer.DeleteOne(e.cursorY, e.cursorX)
+		if err != nil {
+			e.screen.Beep()
+			return nil
+		}
+	}
+	return nil
+}
+
+// cmdUndo undoes count transactions.
+func cmdUndo(e *Editor, count int) error {
+	for i := 0; i < count; i++ {
+		var err error
+		e.cursorY, e.cursorX, err = e.buffer.Undo()
+		if err != nil {
+			e.screen.Beep()
+			return nil
+		}
+	}
+	return nil
+}
+
+// cmdRedo redoes count transactions.
+func cmdRedo(e *Editor, count int) error {
+	for i := 0; i < count; i++ {
+		var err error
+		e.cursorY, e.cursorX, err = e.buffer.Redo()
+		if err != nil {
+			e.screen.Beep()
+			return nil
+		}
+	}
+	return nil
+}
+
+// cmdSave saves the file and reports the result in the message bar.
+func cmdSave(e *Editor, count int) error {
+	if err := e.Save(); err != nil {
+		e.setMessage(err.Error())
+		e.screen.Beep()
+		return nil
+	}
+	e.setMessage("saved")
+	return nil
+}
+
+// selfInsert inserts the pressed rune count times; it's the fallback for
+// any printable rune with no explicit keymap binding.
+func selfInsert(e *Editor, count int, r rune) error {
+	for i := 0; i < count; i++ {
+		var err error
+		e.cursorY, e.cursorX, err = e.buffer.InsertOne(r, e.cursorY, e.cursorX)
+		if err != nil {
+			e.screen.Beep()
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// editor/keymap_test.go
+
+package editor
+
+import (
+	"testing"
+
+	"github.com/cubeflix/edit/buffer"
+	"github.com/gdamore/tcell"
+)
+
+func newTestEditor(t *testing.T) *Editor {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("screen.Init: %v", err)
+	}
+	t.Cleanup(screen.Fini)
+
+	buf := buffer.NewBuffer(MaxBufferSize, MaxLineSize)
+	lines := make([]*buffer.BufferLine, 20)
+	for i := range lines {
+		lines[i] = buffer.NewBufferLine(MaxLineSize)
+	}
+	buf.SetData(lines)
+
+	return &Editor{
+		screen:    screen,
+		buffer:    buf,
+		keymap:    DefaultKeymap(),
+		QuitTimes: DefaultQuitTimes,
+	}
+}
+
+func runeKey(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+}
+
+func altRuneKey(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt)
+}
+
+func TestAltDigitsAccumulateIntoNumericPrefixInDefaultKeymap(t *testing.T) {
+	e := newTestEditor(t)
+
+	e.handleKeyPress(altRuneKey('1'))
+	e.handleKeyPress(runeKey('2'))
+	e.handleKeyPress(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+
+	if e.cursorY != 12 {
+		t.Fatalf("cursorY = %d, want 12 (Alt-1 2 <Down> should move down 12 lines)", e.cursorY)
+	}
+	if e.countPending || e.pendingCount != "" {
+		t.Fatalf("expected the prefix to be consumed, got pending=%v count=%q", e.countPending, e.pendingCount)
+	}
+}
+
+func TestBareDigitsSelfInsertInDefaultKeymap(t *testing.T) {
+	e := newTestEditor(t)
+
+	e.handleKeyPress(runeKey('5'))
+	if got := string(e.buffer.Data()[0].Data()); got != "5" {
+		t.Fatalf("row 0 = %q, want %q: the default keymap still self-inserts plain digits", got, "5")
+	}
+}
+
+func TestBareDigitsAccumulateIntoNumericPrefixWhenSelfInsertDisabled(t *testing.T) {
+	e := newTestEditor(t)
+	e.keymap = NewKeymap()
+	e.keymap.SelfInsert = false
+	e.keymap.Bind(KeyEvent{Key: tcell.KeyDown}, cmdDown)
+
+	e.handleKeyPress(runeKey('1'))
+	e.handleKeyPress(runeKey('2'))
+	e.handleKeyPress(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+
+	if e.cursorY != 12 {
+		t.Fatalf("cursorY = %d, want 12 (12<Down> should move down 12 lines)", e.cursorY)
+	}
+	if got := string(e.buffer.Data()[0].Data()); got != "" {
+		t.Fatalf("row 0 = %q, want unchanged: a pending-count digit must not self-insert", got)
+	}
+}
+
+func TestKeymapCanDisableSelfInsert(t *testing.T) {
+	e := newTestEditor(t)
+	e.keymap = NewKeymap()
+	e.keymap.SelfInsert = false
+
+	e.handleKeyPress(runeKey('a'))
+
+	if got := string(e.buffer.Data()[0].Data()); got != "" {
+		t.Fatalf("row 0 = %q, want unchanged: SelfInsert=false should make an unbound letter a no-op", got)
+	}
+}
+
+func TestDefaultKeymapStillSelfInserts(t *testing.T) {
+	e := newTestEditor(t)
+
+	e.handleKeyPress(runeKey('a'))
+
+	if got := string(e.buffer.Data()[0].Data()); got != "a" {
+		t.Fatalf("row 0 = %q, want %q", got, "a")
+	}
+}
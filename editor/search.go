@@ -0,0 +1,146 @@
+// editor/search.go
+// Incremental regexp search: a `/` (or Ctrl-F) minibuffer on the last
+// screen row that live-highlights matches as the pattern is typed, with
+// n/N stepping once the pattern is confirmed.
+
+package editor
+
+import (
+	"github.com/cubeflix/edit/search"
+	"github.com/gdamore/tcell"
+)
+
+// searchMatchStyle highlights matches distinctly from ordinary text.
+var searchMatchStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow)
+
+// searchBarStyle draws the minibuffer prompt/pattern on the last row.
+var searchBarStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+
+// cmdStartSearch opens the search minibuffer, remembering the cursor
+// position to restore if the search is cancelled before being confirmed.
+func cmdStartSearch(e *Editor, count int) error {
+	e.search = search.New(e.buffer)
+	e.searchTyping = true
+	e.searchOrigY, e.searchOrigX = e.cursorY, e.cursorX
+	return nil
+}
+
+// closeSearch ends the search session, clearing highlights and the
+// minibuffer.
+func (e *Editor) closeSearch() {
+	e.search = nil
+	e.searchTyping = false
+}
+
+// searchJump moves the cursor to (row, col) and re-centers the viewport
+// on it, the way kilo-style "jump to line" does.
+func (e *Editor) searchJump(row, col int) {
+	e.cursorY, e.cursorX = row, col
+	e.top = e.cursorY - e.textHeight()/2
+	if e.top < 0 {
+		e.top = 0
+	}
+	e.left = 0
+	if e.cursorX >= e.width {
+		e.left = e.cursorX - e.width + 1
+	}
+}
+
+// handleSearchKey handles a keypress while a search session is active,
+// either editing the pattern (searchTyping) or navigating matches.
+func (e *Editor) handleSearchKey(event *tcell.EventKey) bool {
+	if e.searchTyping {
+		switch {
+		case event.Key() == tcell.KeyEscape:
+			// Cancel: restore the cursor to where the search started.
+			e.cursorY, e.cursorX = e.searchOrigY, e.searchOrigX
+			e.closeSearch()
+			return true
+		case event.Key() == tcell.KeyEnter:
+			e.searchTyping = false
+			if row, col, ok := e.search.First(e.cursorY, e.cursorX); ok {
+				e.searchJump(row, col)
+			} else {
+				e.screen.Beep()
+			}
+			return true
+		case event.Key() == tcell.KeyBackspace:
+			e.search.Backspace()
+			return true
+		case event.Key() == tcell.KeyCtrlT:
+			// Toggle case-insensitive matching.
+			e.search.ToggleIgnoreCase()
+			return true
+		case event.Key() == tcell.KeyRune:
+			e.search.AppendRune(event.Rune())
+			return true
+		default:
+			return true
+		}
+	}
+
+	// Confirmed: 'n'/'N' step through matches, anything else ends the
+	// search session and is then handled normally.
+	switch {
+	case event.Key() == tcell.KeyEscape:
+		e.closeSearch()
+		return true
+	case event.Key() == tcell.KeyRune && event.Rune() == 'n':
+		if row, col, ok := e.search.Next(e.cursorY, e.cursorX); ok {
+			e.searchJump(row, col)
+		} else {
+			e.screen.Beep()
+		}
+		return true
+	case event.Key() == tcell.KeyRune && event.Rune() == 'N':
+		if row, col, ok := e.search.Prev(e.cursorY, e.cursorX); ok {
+			e.searchJump(row, col)
+		} else {
+			e.screen.Beep()
+		}
+		return true
+	default:
+		e.closeSearch()
+		return e.handleKeyPress(event)
+	}
+}
+
+// renderSearch overlays match highlights on visible lines and draws the
+// minibuffer on the last screen row. Called by Render after the plain
+// text pass.
+func (e *Editor) renderSearch() {
+	for i := 0; i < e.textHeight(); i++ {
+		line := e.top + i
+		if line > e.buffer.Size()-1 {
+			break
+		}
+		for _, m := range e.search.MatchesForLine(line) {
+			for col := m.Start; col < m.End; col++ {
+				if col < e.left || col >= e.left+e.width {
+					continue
+				}
+				r := e.buffer.Data()[line].Data()[col]
+				e.frame.Set(col-e.left, i, r, searchMatchStyle)
+			}
+		}
+	}
+
+	msg := "/" + e.search.Pattern()
+	if e.search.Err() != nil {
+		msg = "search: " + e.search.Err().Error()
+	} else if !e.searchTyping {
+		if e.search.IgnoreCase() {
+			msg += " (ignore case) [n/N to step, Esc to end]"
+		} else {
+			msg += " [n/N to step, Esc to end]"
+		}
+	}
+	row := e.height - 1
+	for i := 0; i < e.width; i++ {
+		ch := ' '
+		if i < len(msg) {
+			ch = rune(msg[i])
+		}
+		e.frame.Set(i, row, ch, searchBarStyle)
+	}
+}
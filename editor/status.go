@@ -0,0 +1,113 @@
+// editor/status.go
+// Persistent status bar and transient message bar on the last two screen
+// rows, plus quit-with-confirmation for unsaved changes.
+
+package editor
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// statusBarStyle draws the persistent status line (second-to-last row).
+var statusBarStyle = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
+
+// messageBarStyle draws the transient message area (last row).
+var messageBarStyle = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
+
+// setMessage sets the transient message-bar message, timestamped so
+// renderMessageBar can let it expire after messageTimeout.
+func (e *Editor) setMessage(msg string) {
+	e.message = msg
+	e.messageAt = time.Now()
+}
+
+// attemptQuit handles a quit request (Escape or Ctrl-Q). A clean buffer
+// quits immediately; a dirty one requires QuitTimes consecutive presses,
+// counting down and warning in the message area in between.
+func (e *Editor) attemptQuit() bool {
+	if !e.buffer.Dirty() {
+		e.Exit()
+		return false
+	}
+
+	if e.quitRemaining <= 0 {
+		e.quitRemaining = e.QuitTimes
+	}
+	e.quitRemaining--
+	if e.quitRemaining <= 0 {
+		e.Exit()
+		return false
+	}
+
+	e.setMessage(pluralPressesWarning(e.quitRemaining))
+	return true
+}
+
+// pluralPressesWarning formats the "unsaved changes" quit warning.
+func pluralPressesWarning(remaining int) string {
+	times := "times"
+	if remaining == 1 {
+		times = "time"
+	}
+	return "unsaved changes! press quit " + strconv.Itoa(remaining) + " more " + times + " to quit"
+}
+
+// bufferByteSize returns the number of bytes Save would write for the
+// buffer's current content: every line plus its line separator.
+func (e *Editor) bufferByteSize() int {
+	lines := e.buffer.Data()
+	total := 0
+	for _, l := range lines {
+		total += len(string(l.Data()))
+	}
+	total += len(lines) * len("\r\n")
+	return total
+}
+
+// renderStatusBar draws the persistent status line on the second-to-last
+// row: the file name, a dirty marker, the cursor position, the total line
+// count, and the file size.
+func (e *Editor) renderStatusBar() {
+	if e.height < 2 {
+		return
+	}
+
+	left := e.file
+	if e.buffer.Dirty() {
+		left += " [modified]"
+	}
+	right := strconv.Itoa(e.cursorY+1) + ":" + strconv.Itoa(e.cursorX+1) +
+		"  " + strconv.Itoa(e.buffer.Size()) + " lines, " + strconv.Itoa(e.bufferByteSize()) + "B"
+
+	row := e.height - 2
+	for i := 0; i < e.width; i++ {
+		ch := ' '
+		switch {
+		case i < len(left):
+			ch = rune(left[i])
+		case i >= e.width-len(right):
+			ch = rune(right[i-(e.width-len(right))])
+		}
+		e.frame.Set(i, row, ch, statusBarStyle)
+	}
+}
+
+// renderMessageBar draws the transient message set by setMessage on the
+// last row, clearing it once it has been visible for messageTimeout.
+func (e *Editor) renderMessageBar() {
+	if e.message != "" && time.Since(e.messageAt) > messageTimeout {
+		e.message = ""
+	}
+
+	row := e.height - 1
+	for i := 0; i < e.width; i++ {
+		ch := ' '
+		if i < len(e.message) {
+			ch = rune(e.message[i])
+		}
+		e.frame.Set(i, row, ch, messageBarStyle)
+	}
+}
@@ -0,0 +1,21 @@
+// editor/status_test.go
+
+package editor
+
+import "testing"
+
+func TestBufferByteSizeCountsLinesAndSeparators(t *testing.T) {
+	e := newTestEditor(t)
+	lines := e.buffer.Data()
+	if _, _, err := e.buffer.InsertOne('h', 0, 0); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if _, _, err := e.buffer.InsertOne('i', 0, 1); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	want := len("hi") + len(lines)*len("\r\n")
+	if got := e.bufferByteSize(); got != want {
+		t.Fatalf("bufferByteSize() = %d, want %d", got, want)
+	}
+}
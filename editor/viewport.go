@@ -0,0 +1,33 @@
+// editor/viewport.go
+// Cursor-viewport scroll math, pulled out of Render so it can be
+// unit-tested without a live tcell screen.
+
+package editor
+
+// viewport is the visible window into the buffer: (top, left) is the
+// buffer position shown in the window's top-left corner, and
+// (width, height) is how much of the buffer is visible.
+type viewport struct {
+	top, left     int
+	width, height int
+}
+
+// scroll moves top/left the minimum amount needed to bring (cursorY,
+// cursorX) back within view, in a single jump rather than one line/column
+// at a time. That matters once a command can move the cursor by an
+// arbitrary count in one keypress (e.g. a numeric-prefix "30" then Down):
+// Render only calls scroll once per keypress, so incrementing by one would
+// leave the cursor far outside the rendered range.
+func (v viewport) scroll(cursorY, cursorX int) viewport {
+	if cursorY >= v.top+v.height {
+		v.top = cursorY - v.height + 1
+	} else if cursorY < v.top {
+		v.top = cursorY
+	}
+	if cursorX >= v.left+v.width {
+		v.left = cursorX - v.width + 1
+	} else if cursorX < v.left {
+		v.left = cursorX
+	}
+	return v
+}
@@ -0,0 +1,59 @@
+// editor/viewport_test.go
+
+package editor
+
+import "testing"
+
+func TestViewportScrollDown(t *testing.T) {
+	v := viewport{top: 0, left: 0, width: 10, height: 5}
+	v = v.scroll(5, 0)
+	if v.top != 1 {
+		t.Errorf("top = %d, want 1", v.top)
+	}
+}
+
+func TestViewportScrollUp(t *testing.T) {
+	v := viewport{top: 5, left: 0, width: 10, height: 5}
+	v = v.scroll(4, 0)
+	if v.top != 4 {
+		t.Errorf("top = %d, want 4", v.top)
+	}
+}
+
+func TestViewportScrollRight(t *testing.T) {
+	v := viewport{top: 0, left: 0, width: 10, height: 5}
+	v = v.scroll(0, 10)
+	if v.left != 1 {
+		t.Errorf("left = %d, want 1", v.left)
+	}
+}
+
+func TestViewportNoScrollWithinView(t *testing.T) {
+	v := viewport{top: 2, left: 3, width: 10, height: 5}
+	got := v.scroll(4, 5)
+	if got != v {
+		t.Errorf("scroll() = %+v, want unchanged %+v", got, v)
+	}
+}
+
+func TestViewportScrollDownLargeJump(t *testing.T) {
+	// A numeric-prefix command can move the cursor many rows in a single
+	// keypress; scroll must bring the cursor into view in one call, not
+	// creep toward it one row at a time.
+	v := viewport{top: 0, left: 0, width: 10, height: 5}
+	v = v.scroll(30, 0)
+	if v.top != 26 {
+		t.Errorf("top = %d, want 26 (cursor row 30 should end at the bottom of a 5-row view)", v.top)
+	}
+	if 30 < v.top || 30 >= v.top+v.height {
+		t.Errorf("cursorY 30 not within view after scroll: top=%d height=%d", v.top, v.height)
+	}
+}
+
+func TestViewportScrollRightLargeJump(t *testing.T) {
+	v := viewport{top: 0, left: 0, width: 10, height: 5}
+	v = v.scroll(0, 40)
+	if v.left != 31 {
+		t.Errorf("left = %d, want 31 (cursor col 40 should end at the right edge of a 10-col view)", v.left)
+	}
+}
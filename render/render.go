@@ -0,0 +1,92 @@
+// render/render.go
+// Package render provides a buffered, diffing frame renderer: callers
+// paint into an in-memory cell grid and Flush only touches the terminal
+// cells that actually changed, instead of redrawing the whole screen.
+
+package render
+
+import "github.com/gdamore/tcell"
+
+// Cell is a single screen cell: a rune plus the style to draw it with.
+type Cell struct {
+	Ch    rune
+	Style tcell.Style
+}
+
+// Buffer is an in-memory grid of cells for one frame, diffed against the
+// previous frame on Flush so only changed cells are sent to the screen.
+type Buffer struct {
+	width, height int
+	cur, prev     [][]Cell
+}
+
+// NewBuffer creates a Buffer sized for a width x height screen.
+func NewBuffer(width, height int) *Buffer {
+	b := &Buffer{}
+	b.Resize(width, height)
+	return b
+}
+
+// Resize changes the buffer's dimensions, discarding the previous frame so
+// the next Flush repaints every cell (the screen was just resized).
+func (b *Buffer) Resize(width, height int) {
+	b.width, b.height = width, height
+	b.cur = makeGrid(width, height)
+	b.prev = nil
+}
+
+// makeGrid allocates a width x height grid of blank cells.
+func makeGrid(width, height int) [][]Cell {
+	grid := make([][]Cell, height)
+	for y := range grid {
+		grid[y] = make([]Cell, width)
+		for x := range grid[y] {
+			grid[y][x] = Cell{Ch: ' '}
+		}
+	}
+	return grid
+}
+
+// Clear blanks the current frame so layers can repaint it from scratch.
+// It does not touch the previous frame used for diffing.
+func (b *Buffer) Clear() {
+	for y := range b.cur {
+		for x := range b.cur[y] {
+			b.cur[y][x] = Cell{Ch: ' '}
+		}
+	}
+}
+
+// Set paints a single cell of the current frame. Out-of-bounds coordinates
+// are ignored so callers don't need their own bounds checks.
+func (b *Buffer) Set(x, y int, ch rune, style tcell.Style) {
+	if x < 0 || y < 0 || y >= b.height || x >= b.width {
+		return
+	}
+	b.cur[y][x] = Cell{Ch: ch, Style: style}
+}
+
+// Flush diffs the current frame against the previous one, calling
+// screen.SetContent only for cells that changed, then does a single
+// screen.Show.
+func (b *Buffer) Flush(screen tcell.Screen) {
+	for y := 0; y < b.height; y++ {
+		for x := 0; x < b.width; x++ {
+			cell := b.cur[y][x]
+			if b.prev != nil && b.prev[y][x] == cell {
+				continue
+			}
+			screen.SetContent(x, y, cell.Ch, nil, cell.Style)
+		}
+	}
+	screen.Show()
+
+	prev := b.prev
+	if prev == nil {
+		prev = makeGrid(b.width, b.height)
+	}
+	for y := range b.cur {
+		copy(prev[y], b.cur[y])
+	}
+	b.prev = prev
+}
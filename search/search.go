@@ -0,0 +1,194 @@
+// search/search.go
+// Package search provides incremental regexp search over a buffer.Buffer.
+
+package search
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/cubeflix/edit/buffer"
+)
+
+// Match is a single regexp match on one line, given as rune columns
+// [Start, End).
+type Match struct {
+	Start int
+	End   int
+}
+
+// Search tracks an incremental regexp search against a buffer.Buffer: the
+// pattern text, the compiled regexp, and a per-line match cache that's
+// invalidated whenever the buffer's content changes.
+type Search struct {
+	buf *buffer.Buffer
+
+	pattern    string
+	ignoreCase bool
+	re         *regexp.Regexp
+	err        error
+
+	version int
+	lines   map[int][]Match
+}
+
+// New creates a Search bound to buf, starting with an empty pattern (which
+// matches nothing).
+func New(buf *buffer.Buffer) *Search {
+	return &Search{buf: buf, lines: make(map[int][]Match)}
+}
+
+// Pattern returns the raw (un-case-folded) pattern text typed so far.
+func (s *Search) Pattern() string {
+	return s.pattern
+}
+
+// IgnoreCase reports whether the search is currently case-insensitive.
+func (s *Search) IgnoreCase() bool {
+	return s.ignoreCase
+}
+
+// Err returns the error from the last failed compile, if the current
+// pattern is invalid. Matches from the last successfully compiled pattern
+// remain in effect until the pattern becomes valid (or empty) again.
+func (s *Search) Err() error {
+	return s.err
+}
+
+// AppendRune appends r to the pattern and recompiles.
+func (s *Search) AppendRune(r rune) {
+	s.setPattern(s.pattern + string(r))
+}
+
+// Backspace removes the last rune of the pattern and recompiles.
+func (s *Search) Backspace() {
+	if s.pattern == "" {
+		return
+	}
+	runes := []rune(s.pattern)
+	s.setPattern(string(runes[:len(runes)-1]))
+}
+
+// ToggleIgnoreCase flips case sensitivity and recompiles the pattern.
+func (s *Search) ToggleIgnoreCase() {
+	s.ignoreCase = !s.ignoreCase
+	s.setPattern(s.pattern)
+}
+
+// setPattern recompiles the regexp for pattern. An empty pattern clears
+// all matches. An invalid pattern records err (see Err) but
+// keeps whatever was compiled previously, so matches don't disappear
+// while the user is still typing a longer pattern.
+func (s *Search) setPattern(pattern string) {
+	s.pattern = pattern
+	if pattern == "" {
+		s.re = nil
+		s.err = nil
+		s.invalidate()
+		return
+	}
+
+	expr := pattern
+	if s.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.re = re
+	s.err = nil
+	s.invalidate()
+}
+
+// invalidate drops the per-line match cache.
+func (s *Search) invalidate() {
+	s.lines = make(map[int][]Match)
+}
+
+// refresh drops the cache if the buffer has changed since it was built.
+func (s *Search) refresh() {
+	if v := s.buf.Version(); v != s.version {
+		s.version = v
+		s.invalidate()
+	}
+}
+
+// MatchesForLine returns the (cached) matches on row, computed against
+// BufferLine.Data() for that row.
+func (s *Search) MatchesForLine(row int) []Match {
+	s.refresh()
+	if m, ok := s.lines[row]; ok {
+		return m
+	}
+	if s.re == nil || row < 0 || row >= s.buf.Size() {
+		return nil
+	}
+
+	line := string(s.buf.Data()[row].Data())
+	idxs := s.re.FindAllStringIndex(line, -1)
+	matches := make([]Match, 0, len(idxs))
+	for _, idx := range idxs {
+		start := utf8.RuneCountInString(line[:idx[0]])
+		end := start + utf8.RuneCountInString(line[idx[0]:idx[1]])
+		matches = append(matches, Match{Start: start, End: end})
+	}
+	s.lines[row] = matches
+	return matches
+}
+
+// First returns the first match at or after (row, col), wrapping around
+// the document. ok is false if there are no matches at all.
+func (s *Search) First(row, col int) (int, int, bool) {
+	return s.scanForward(row, col, true)
+}
+
+// Next returns the next match strictly after (row, col), wrapping around
+// the document.
+func (s *Search) Next(row, col int) (int, int, bool) {
+	return s.scanForward(row, col, false)
+}
+
+func (s *Search) scanForward(row, col int, inclusive bool) (int, int, bool) {
+	n := s.buf.Size()
+	if s.re == nil || n == 0 {
+		return row, col, false
+	}
+	for i := 0; i <= n; i++ {
+		r := (row + i) % n
+		for _, m := range s.MatchesForLine(r) {
+			if i == 0 {
+				if inclusive && m.Start < col {
+					continue
+				}
+				if !inclusive && m.Start <= col {
+					continue
+				}
+			}
+			return r, m.Start, true
+		}
+	}
+	return row, col, false
+}
+
+// Prev returns the previous match strictly before (row, col), wrapping
+// around the document.
+func (s *Search) Prev(row, col int) (int, int, bool) {
+	n := s.buf.Size()
+	if s.re == nil || n == 0 {
+		return row, col, false
+	}
+	for i := 0; i <= n; i++ {
+		r := ((row-i)%n + n) % n
+		matches := s.MatchesForLine(r)
+		for j := len(matches) - 1; j >= 0; j-- {
+			m := matches[j]
+			if i == 0 && m.Start >= col {
+				continue
+			}
+			return r, m.Start, true
+		}
+	}
+	return row, col, false
+}
@@ -0,0 +1,104 @@
+// search/search_test.go
+
+package search
+
+import (
+	"testing"
+
+	"github.com/cubeflix/edit/buffer"
+)
+
+func makeBuffer(lines ...string) *buffer.Buffer {
+	buf := buffer.NewBuffer(1<<20, 1<<16)
+	blines := make([]*buffer.BufferLine, len(lines))
+	for i, l := range lines {
+		bl := buffer.NewBufferLine(1 << 16)
+		bl.Insert([]rune(l), 0)
+		blines[i] = bl
+	}
+	buf.SetData(blines)
+	return buf
+}
+
+func TestMatchesForLine(t *testing.T) {
+	buf := makeBuffer("foo bar foo", "baz")
+	s := New(buf)
+	s.AppendRune('f')
+	s.AppendRune('o')
+	s.AppendRune('o')
+
+	matches := s.MatchesForLine(0)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d: %v", len(matches), matches)
+	}
+	if matches[0].Start != 0 || matches[0].End != 3 {
+		t.Errorf("unexpected first match: %+v", matches[0])
+	}
+	if matches[1].Start != 8 || matches[1].End != 11 {
+		t.Errorf("unexpected second match: %+v", matches[1])
+	}
+}
+
+func TestEmptyPatternClearsMatches(t *testing.T) {
+	buf := makeBuffer("foo")
+	s := New(buf)
+	s.AppendRune('f')
+	if len(s.MatchesForLine(0)) == 0 {
+		t.Fatal("expected a match before clearing the pattern")
+	}
+	s.Backspace()
+	if matches := s.MatchesForLine(0); len(matches) != 0 {
+		t.Errorf("expected no matches with an empty pattern, got %v", matches)
+	}
+}
+
+func TestInvalidRegexKeepsPreviousMatches(t *testing.T) {
+	buf := makeBuffer("foo")
+	s := New(buf)
+	s.AppendRune('f')
+	if len(s.MatchesForLine(0)) == 0 {
+		t.Fatal("expected a match for a valid pattern")
+	}
+
+	s.AppendRune('[')
+	if s.Err() == nil {
+		t.Fatal("expected an error for an unterminated character class")
+	}
+	if matches := s.MatchesForLine(0); len(matches) == 0 {
+		t.Errorf("expected matches from the last valid pattern to survive, got none")
+	}
+}
+
+func TestCacheInvalidatesOnBufferChange(t *testing.T) {
+	buf := makeBuffer("foo")
+	s := New(buf)
+	s.AppendRune('f')
+	if len(s.MatchesForLine(0)) != 1 {
+		t.Fatal("expected one match")
+	}
+
+	if _, _, err := buf.InsertOne('f', 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if matches := s.MatchesForLine(0); len(matches) != 2 {
+		t.Errorf("expected 2 matches after the buffer changed, got %d: %v", len(matches), matches)
+	}
+}
+
+func TestNextWrapsAround(t *testing.T) {
+	buf := makeBuffer("foo", "bar", "foo")
+	s := New(buf)
+	s.AppendRune('f')
+	s.AppendRune('o')
+	s.AppendRune('o')
+
+	row, col, ok := s.First(1, 0)
+	if !ok || row != 2 || col != 0 {
+		t.Fatalf("First(1,0) = (%d, %d, %v), want (2, 0, true)", row, col, ok)
+	}
+
+	row, col, ok = s.Next(row, col)
+	if !ok || row != 0 || col != 0 {
+		t.Fatalf("Next wrapped to (%d, %d, %v), want (0, 0, true)", row, col, ok)
+	}
+}
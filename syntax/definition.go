@@ -0,0 +1,69 @@
+// syntax/definition.go
+// Loading Definitions from declarative rule files, and the built-in
+// Go/Markdown definitions shipped with the editor.
+
+package syntax
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"path/filepath"
+)
+
+// ParseDefinition parses a Definition from JSON, the rule-file format
+// given by the json tags on Definition and RuleDef.
+func ParseDefinition(raw []byte) (*Definition, error) {
+	var def Definition
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+//go:embed definitions/*.json
+var builtinDefs embed.FS
+
+// builtin is a built-in Definition, compiled once at package init.
+type builtin struct {
+	def *Definition
+	hl  *Highlighter
+}
+
+var builtins []builtin
+
+func init() {
+	entries, err := builtinDefs.ReadDir("definitions")
+	if err != nil {
+		panic("syntax: reading built-in definitions: " + err.Error())
+	}
+	for _, entry := range entries {
+		raw, err := builtinDefs.ReadFile(path.Join("definitions", entry.Name()))
+		if err != nil {
+			panic("syntax: reading built-in definition " + entry.Name() + ": " + err.Error())
+		}
+		def, err := ParseDefinition(raw)
+		if err != nil {
+			panic("syntax: parsing built-in definition " + entry.Name() + ": " + err.Error())
+		}
+		hl, err := Compile(def)
+		if err != nil {
+			panic("syntax: compiling built-in definition " + entry.Name() + ": " + err.Error())
+		}
+		builtins = append(builtins, builtin{def: def, hl: hl})
+	}
+}
+
+// ForFile returns the built-in Highlighter whose filetypes glob matches
+// file's base name, if any.
+func ForFile(file string) (*Highlighter, bool) {
+	base := filepath.Base(file)
+	for _, b := range builtins {
+		for _, pattern := range b.def.Filetypes {
+			if ok, _ := filepath.Match(pattern, base); ok {
+				return b.hl, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,63 @@
+// syntax/highlights.go
+// Per-buffer, per-line highlight cache.
+
+package syntax
+
+import "github.com/cubeflix/edit/buffer"
+
+// Highlights caches per-line Spans and end-of-line States for a buffer,
+// recomputing only the suffix invalidated since the buffer's DirtyFrom
+// marker instead of the whole document on every edit.
+type Highlights struct {
+	buf *buffer.Buffer
+	hl  *Highlighter
+
+	version int
+	spans   [][]Span
+	states  []State
+}
+
+// NewHighlights creates a per-line highlight cache for buf using hl.
+func NewHighlights(buf *buffer.Buffer, hl *Highlighter) *Highlights {
+	return &Highlights{buf: buf, hl: hl}
+}
+
+// refresh recomputes lines invalidated since the last call, up through upTo,
+// carrying each line's end-of-line State into the next. It never scans past
+// upTo: callers that only need a handful of visible rows (the renderer) pay
+// for those rows, not the whole document.
+func (h *Highlights) refresh(upTo int) {
+	if v := h.buf.Version(); v != h.version {
+		h.version = v
+		from := h.buf.DirtyFrom()
+		h.buf.ClearDirty()
+		if from < len(h.spans) {
+			h.spans = h.spans[:from]
+			h.states = h.states[:from]
+		}
+	}
+
+	n := h.buf.Size()
+	if upTo >= n {
+		upTo = n - 1
+	}
+	for row := len(h.spans); row <= upTo; row++ {
+		in := State{}
+		if row > 0 {
+			in = h.states[row-1]
+		}
+		spans, out := h.hl.HighlightLine(h.buf.Data()[row].Data(), in)
+		h.spans = append(h.spans, spans)
+		h.states = append(h.states, out)
+	}
+}
+
+// SpansForLine returns the (cached) spans for row, extending the cache only
+// as far as row rather than recomputing the whole document.
+func (h *Highlights) SpansForLine(row int) []Span {
+	h.refresh(row)
+	if row < 0 || row >= len(h.spans) {
+		return nil
+	}
+	return h.spans[row]
+}
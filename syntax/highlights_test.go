@@ -0,0 +1,76 @@
+// syntax/highlights_test.go
+
+package syntax
+
+import (
+	"testing"
+
+	"github.com/cubeflix/edit/buffer"
+)
+
+func lineOf(t *testing.T, s string) *buffer.BufferLine {
+	t.Helper()
+	l := buffer.NewBufferLine(1 << 20)
+	if err := l.Insert([]rune(s), 0); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	return l
+}
+
+func bufOf(t *testing.T, lines ...string) *buffer.Buffer {
+	t.Helper()
+	b := buffer.NewBuffer(1<<30, 1<<20)
+	views := make([]*buffer.BufferLine, len(lines))
+	for i, s := range lines {
+		views[i] = lineOf(t, s)
+	}
+	b.SetData(views)
+	return b
+}
+
+func TestSpansForLineOnlyComputesUpToRequestedRow(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{{Pattern: `\bif\b`, Style: "keyword"}},
+	})
+
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = "if x"
+	}
+	b := bufOf(t, lines...)
+	h := NewHighlights(b, hl)
+
+	if _, _, err := b.InsertOne('y', 0, 0); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	spans := h.SpansForLine(2)
+	if len(spans) != 1 || spans[0].Style != "keyword" {
+		t.Fatalf("unexpected spans for row 2: %v", spans)
+	}
+	if len(h.spans) != 3 {
+		t.Fatalf("expected cache to extend only to the requested row (3 entries), got %d", len(h.spans))
+	}
+}
+
+func TestSpansForLineInvalidatesFromEditedRow(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{{Pattern: `\bif\b`, Style: "keyword"}},
+	})
+
+	b := bufOf(t, "if x", "y", "if z")
+	h := NewHighlights(b, hl)
+
+	if spans := h.SpansForLine(2); len(spans) != 1 {
+		t.Fatalf("expected a match on row 2, got %v", spans)
+	}
+
+	if _, _, err := b.DeleteOne(2, 2); err != nil {
+		t.Fatalf("DeleteOne: %v", err)
+	}
+
+	spans := h.SpansForLine(2)
+	if len(spans) != 0 {
+		t.Fatalf("expected the match to disappear once \"if\" was broken, got %v", spans)
+	}
+}
@@ -0,0 +1,248 @@
+// syntax/syntax.go
+// Package syntax tokenizes buffer lines into styled spans according to
+// declarative, per-language rule definitions, for the renderer to apply.
+
+package syntax
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// Span is a styled run of runes on one line, given as rune columns
+// [Start, End) and the name of the style to draw it with (see Theme).
+type Span struct {
+	Start, End int
+	Style      string
+}
+
+// State carries a Highlighter's position across a line boundary, e.g.
+// still inside an unterminated string or an open block comment. The zero
+// State means "not inside any region."
+type State struct {
+	regions []int
+}
+
+// Equal reports whether two States represent the same nesting of regions.
+func (s State) Equal(o State) bool {
+	if len(s.regions) != len(o.regions) {
+		return false
+	}
+	for i := range s.regions {
+		if s.regions[i] != o.regions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Definition is the declarative, on-disk description of one language's
+// syntax: which files it applies to, and its ordered highlighting rules.
+// Rules are tried in order at each position; the first match wins.
+type Definition struct {
+	Filetypes []string  `json:"filetypes"`
+	Rules     []RuleDef `json:"rules"`
+}
+
+// RuleDef is one rule in a Definition: either a Pattern+Style (a single
+// regexp match), or a Start/End region (e.g. a string or block comment)
+// whose interior is highlighted by its own nested Rules.
+type RuleDef struct {
+	Pattern string `json:"pattern,omitempty"`
+	Style   string `json:"style,omitempty"`
+
+	Start string    `json:"start,omitempty"`
+	End   string    `json:"end,omitempty"`
+	Rules []RuleDef `json:"rules,omitempty"`
+}
+
+// rule is a compiled RuleDef: either a pattern rule (pattern set) or a
+// region rule (regionIdx indexes into Highlighter.regions).
+type rule struct {
+	pattern *regexp.Regexp
+	style   string
+
+	regionIdx int
+}
+
+// compiledRegion is a compiled region rule: its delimiters, its own
+// style, and the nested ruleset used while inside it.
+type compiledRegion struct {
+	style      string
+	start, end *regexp.Regexp
+	rules      []rule
+}
+
+// Highlighter tokenizes lines into styled Spans according to a compiled
+// Definition. It holds no per-buffer state itself; callers carry a State
+// between calls to HighlightLine to support multi-line constructs.
+type Highlighter struct {
+	rules   []rule
+	regions []*compiledRegion
+}
+
+// Compile compiles def into a Highlighter, or returns an error if any
+// pattern in it fails to parse as a Go regexp.
+func Compile(def *Definition) (*Highlighter, error) {
+	h := &Highlighter{}
+	rules, err := h.compileRules(def.Rules)
+	if err != nil {
+		return nil, err
+	}
+	h.rules = rules
+	return h, nil
+}
+
+// compileRules compiles defs into rules, registering any regions it finds
+// into h.regions so HighlightLine can reference them by index from State.
+func (h *Highlighter) compileRules(defs []RuleDef) ([]rule, error) {
+	rules := make([]rule, 0, len(defs))
+	for _, d := range defs {
+		if d.Start != "" {
+			start, err := regexp.Compile(d.Start)
+			if err != nil {
+				return nil, err
+			}
+			end, err := regexp.Compile(d.End)
+			if err != nil {
+				return nil, err
+			}
+			nested, err := h.compileRules(d.Rules)
+			if err != nil {
+				return nil, err
+			}
+			idx := len(h.regions)
+			h.regions = append(h.regions, &compiledRegion{style: d.Style, start: start, end: end, rules: nested})
+			rules = append(rules, rule{regionIdx: idx})
+			continue
+		}
+
+		pattern, err := regexp.Compile(d.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule{pattern: pattern, style: d.Style})
+	}
+	return rules, nil
+}
+
+// HighlightLine tokenizes line starting in stateIn, returning the styled
+// spans found and the state to pass into the next line.
+func (h *Highlighter) HighlightLine(line []rune, stateIn State) ([]Span, State) {
+	pos := 0
+	n := len(line)
+	var spans []Span
+	stack := append([]int(nil), stateIn.regions...)
+
+	for pos < n {
+		rules := h.rules
+		var region *compiledRegion
+		if len(stack) > 0 {
+			region = h.regions[stack[len(stack)-1]]
+			rules = region.rules
+		}
+
+		if region != nil {
+			s, end, closed := scanRegion(line, pos, n, region)
+			spans = append(spans, s...)
+			pos = end
+			if closed {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			break
+		}
+
+		rest := string(line[pos:])
+		matched := false
+		for _, r := range rules {
+			if r.pattern != nil {
+				loc := r.pattern.FindStringIndex(rest)
+				if loc == nil || loc[0] != 0 {
+					continue
+				}
+				end := pos + runeLen(rest, loc[1])
+				spans = append(spans, Span{Start: pos, End: end, Style: r.style})
+				pos = end
+				matched = true
+				break
+			}
+
+			reg := h.regions[r.regionIdx]
+			loc := reg.start.FindStringIndex(rest)
+			if loc == nil || loc[0] != 0 {
+				continue
+			}
+			stack = append(stack, r.regionIdx)
+			pos += runeLen(rest, loc[1])
+			matched = true
+			break
+		}
+		if !matched {
+			pos++
+		}
+	}
+
+	return spans, State{regions: stack}
+}
+
+// scanRegion highlights line[from:n] using region's nested rules, defaulting
+// any run not claimed by a nested rule to region.style — e.g. an
+// escape-sequence rule picks out "\n" inside a string while the rest of the
+// string keeps the string color. At each position it tries the nested rules
+// before testing region.end, so an escape rule gets first claim on a
+// delimiter it covers (e.g. `\"`) and the end search never mistakes an
+// escaped delimiter for the real close. It returns the spans found, the
+// position reached, and whether the region's end was actually matched (as
+// opposed to running off the end of the line, in which case the region
+// stays open into the next line). Nested regions-within-regions aren't
+// supported; only pattern rules apply here.
+func scanRegion(line []rune, from, n int, region *compiledRegion) ([]Span, int, bool) {
+	var spans []Span
+	pos := from
+	runStart := from
+	flush := func(end int) {
+		if end > runStart {
+			spans = append(spans, Span{Start: runStart, End: end, Style: region.style})
+		}
+	}
+
+	for pos < n {
+		rest := string(line[pos:n])
+
+		matched := false
+		for _, r := range region.rules {
+			if r.pattern == nil {
+				continue
+			}
+			loc := r.pattern.FindStringIndex(rest)
+			if loc == nil || loc[0] != 0 {
+				continue
+			}
+			flush(pos)
+			end := pos + runeLen(rest, loc[1])
+			spans = append(spans, Span{Start: pos, End: end, Style: r.style})
+			pos = end
+			runStart = pos
+			matched = true
+			break
+		}
+		if matched {
+			continue
+		}
+
+		if loc := region.end.FindStringIndex(rest); loc != nil && loc[0] == 0 {
+			flush(pos)
+			return spans, pos + runeLen(rest, loc[1]), true
+		}
+
+		pos++
+	}
+	flush(n)
+	return spans, n, false
+}
+
+// runeLen returns the number of runes in the first n bytes of s.
+func runeLen(s string, n int) int {
+	return utf8.RuneCountInString(s[:n])
+}
@@ -0,0 +1,129 @@
+// syntax/syntax_test.go
+
+package syntax
+
+import "testing"
+
+func mustCompile(t *testing.T, def *Definition) *Highlighter {
+	t.Helper()
+	hl, err := Compile(def)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return hl
+}
+
+func TestHighlightLinePattern(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{
+			{Pattern: `\bif\b`, Style: "keyword"},
+		},
+	})
+
+	spans, _ := hl.HighlightLine([]rune("if x"), State{})
+	if len(spans) != 1 || spans[0] != (Span{Start: 0, End: 2, Style: "keyword"}) {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+}
+
+func TestHighlightLineRegionCarriesStateAcrossLines(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{
+			{Start: `/\*`, End: `\*/`, Style: "comment"},
+		},
+	})
+
+	spans1, state := hl.HighlightLine([]rune("x /* start"), State{})
+	if len(spans1) != 1 || spans1[0].Style != "comment" {
+		t.Fatalf("line 1 spans: %v", spans1)
+	}
+	if len(state.regions) != 1 {
+		t.Fatalf("expected an open region after line 1, got %+v", state)
+	}
+
+	spans2, state2 := hl.HighlightLine([]rune("still a comment */ code"), state)
+	if len(spans2) == 0 || spans2[0].Style != "comment" {
+		t.Fatalf("line 2 spans: %v", spans2)
+	}
+	if len(state2.regions) != 0 {
+		t.Fatalf("expected the region to close, got %+v", state2)
+	}
+}
+
+func TestHighlightLineNestedRuleInsideRegion(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{
+			{Start: `"`, End: `"`, Style: "string", Rules: []RuleDef{
+				{Pattern: `\\.`, Style: "escape"},
+			}},
+		},
+	})
+
+	spans, state := hl.HighlightLine([]rune(`"a\nb"`), State{})
+	if len(state.regions) != 0 {
+		t.Fatalf("expected the region to close on the same line, got %+v", state)
+	}
+
+	var escapes int
+	for _, s := range spans {
+		if s.Style == "escape" {
+			escapes++
+		}
+	}
+	if escapes != 1 {
+		t.Fatalf("expected 1 escape span, got %d: %v", escapes, spans)
+	}
+}
+
+func TestHighlightLineEscapedDelimiterDoesNotCloseRegion(t *testing.T) {
+	hl := mustCompile(t, &Definition{
+		Rules: []RuleDef{
+			{Start: `"`, End: `"`, Style: "string", Rules: []RuleDef{
+				{Pattern: `\\.`, Style: "escape"},
+			}},
+		},
+	})
+
+	// `"a\"b"`: the escaped quote must not be mistaken for the closing
+	// delimiter, so the region should close at the real quote that follows.
+	spans, state := hl.HighlightLine([]rune(`"a\"b"`), State{})
+	if len(state.regions) != 0 {
+		t.Fatalf("expected the region to close on the same line, got %+v", state)
+	}
+
+	var escapes int
+	for _, s := range spans {
+		if s.Style == "escape" {
+			escapes++
+			if s != (Span{Start: 2, End: 4, Style: "escape"}) {
+				t.Fatalf("unexpected escape span: %+v", s)
+			}
+		}
+	}
+	if escapes != 1 {
+		t.Fatalf("expected 1 escape span, got %d: %v", escapes, spans)
+	}
+}
+
+func TestParseDefinitionJSON(t *testing.T) {
+	raw := []byte(`{"filetypes": ["*.go"], "rules": [{"pattern": "//.*", "style": "comment"}]}`)
+	def, err := ParseDefinition(raw)
+	if err != nil {
+		t.Fatalf("ParseDefinition: %v", err)
+	}
+	if len(def.Filetypes) != 1 || def.Filetypes[0] != "*.go" {
+		t.Fatalf("unexpected filetypes: %v", def.Filetypes)
+	}
+}
+
+func TestForFileMatchesBuiltins(t *testing.T) {
+	if _, ok := ForFile("main.go"); !ok {
+		t.Error("expected a built-in highlighter for main.go")
+	}
+	if _, ok := ForFile("README.md"); !ok {
+		t.Error("expected a built-in highlighter for README.md")
+	}
+	if _, ok := ForFile("data.bin"); ok {
+		t.Error("expected no built-in highlighter for data.bin")
+	}
+}
@@ -0,0 +1,36 @@
+// syntax/theme.go
+// Maps the named styles a Definition's rules reference to concrete
+// tcell.Styles.
+
+package syntax
+
+import "github.com/gdamore/tcell"
+
+// Theme maps style names (keyword, string, comment, number, ...) to the
+// tcell.Style used to draw them.
+type Theme map[string]tcell.Style
+
+// DefaultTheme is a baseline theme covering the style names used by the
+// built-in Go and Markdown definitions, usable on any terminal.
+func DefaultTheme() Theme {
+	return Theme{
+		"keyword": tcell.StyleDefault.Foreground(tcell.ColorBlue),
+		"string":  tcell.StyleDefault.Foreground(tcell.ColorGreen),
+		"escape":  tcell.StyleDefault.Foreground(tcell.ColorTeal),
+		"comment": tcell.StyleDefault.Foreground(tcell.ColorGray),
+		"number":  tcell.StyleDefault.Foreground(tcell.ColorPurple),
+		"heading": tcell.StyleDefault.Foreground(tcell.ColorBlue).Bold(true),
+		"bold":    tcell.StyleDefault.Bold(true),
+		"italic":  tcell.StyleDefault.Italic(true),
+		"link":    tcell.StyleDefault.Foreground(tcell.ColorTeal).Underline(true),
+	}
+}
+
+// Style returns the tcell.Style for name, falling back to the terminal's
+// default unstyled text if name isn't in the theme.
+func (t Theme) Style(name string) tcell.Style {
+	if s, ok := t[name]; ok {
+		return s
+	}
+	return tcell.StyleDefault
+}